@@ -3,6 +3,7 @@ package main
 import (
 	"bed.gg/minecraft-api/v2/src/config"
 	"bed.gg/minecraft-api/v2/src/logger"
+	"bed.gg/minecraft-api/v2/src/yggdrasil"
 	"bed.gg/profile-scanner/v2/scanner"
 	"context"
 	"github.com/go-redis/redis/v9"
@@ -41,23 +42,59 @@ func main() {
 		ipPool = append(ipPool, net.ParseIP(ip))
 	}
 
+	// -- schedule the source ips, skipping ips that used up their budget or got rate limited --
+	scheduler := api.NewIPScheduler(ipPool)
+
+	if config.Ip.Budget > 0 {
+		scheduler.Budget = config.Ip.Budget
+	} else if config.Ip.Budget < 0 {
+		scheduler.Budget = 0
+	}
+
+	if config.Ip.Window > 0 {
+		scheduler.Window = time.Duration(config.Ip.Window) * time.Second
+	}
+
+	if config.Ip.Cooldown > 0 {
+		scheduler.Cooldown = time.Duration(config.Ip.Cooldown) * time.Second
+	}
+
 	// -- meilisearch client --
 	client := meilisearch.NewClient(meilisearch.ClientConfig{
 		Host:   "http://meilisearch:7700",
 		APIKey: "RIGHT_PARENTHESIS-ubr-Auc-NINE",
 	})
 
+	// -- load the yggdrasil public key, profiles with an invalid signature are refused --
+	keyCtx, cancelKey := context.WithTimeout(context.Background(), 10*time.Second)
+	verifier, err := yggdrasil.LoadVerifier(keyCtx, config.Api.YggdrasilPublicKey, config.Api.PublicKeysUrl)
+	cancelKey()
+
+	if err != nil {
+		lg.Warn("No yggdrasil public key loaded, profile signatures will not be verified: %v", err)
+	}
+
 	// -- create the api handler --
 	handler := api.Handler{
-		Logger:   lg,
-		Rdb:      rdb,
-		MSClient: client,
-		Ctx:      context.Background(),
+		Logger:    lg,
+		Rdb:       rdb,
+		MSClient:  client,
+		Ctx:       context.Background(),
+		IPPool:    ipPool,
+		Verifier:  verifier,
+		Scheduler: scheduler,
 	}
 
+	// -- point the handler at the configured upstream hosts --
+	handler.Mojang = api.NewMojangClient(&handler, api.MojangHosts{
+		SessionServer: config.Api.SessionServerUrl,
+		API:           config.Api.MojangApiUrl,
+		Textures:      config.Api.TexturesUrl,
+	})
+
 	// -- create the index --
 	index := client.Index("players")
-	_, err := client.CreateIndex(&meilisearch.IndexConfig{
+	_, err = client.CreateIndex(&meilisearch.IndexConfig{
 		Uid: "players",
 	})
 
@@ -1,29 +1,9 @@
 package mojang
 
-type TextureResponse struct {
-	Timestamp         int64  `json:"timestamp"`
-	ProfileId         string `json:"profileId"`
-	ProfileName       string `json:"profileName"`
-	SignatureRequired bool   `json:"signatureRequired"`
-	Textures          struct {
-		Skin SkinURL `json:"SKIN"`
-		Cape CapeURL `json:"CAPE"`
-	} `json:"textures"`
-}
-
-type SkinURL struct {
-	Url      string `json:"url"`
-	Metadata struct {
-		Model string `json:"model"`
-	} `json:"metadata"`
-}
-
-type CapeURL struct {
-	Url string `json:"url"`
-}
+import "bed.gg/minecraft-api/v2/src/api"
 
 type Document struct {
-	Id       string   `json:"id"`
+	Id       api.UUID `json:"id"`
 	Name     string   `json:"name"`
 	Textures Textures `json:"textures"`
 }
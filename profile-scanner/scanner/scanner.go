@@ -2,19 +2,21 @@ package scanner
 
 import (
 	"bed.gg/minecraft-api/v2/src/api"
+	"bed.gg/minecraft-api/v2/src/yggdrasil"
 	"bed.gg/profile-scanner/v2/mojang"
 	"context"
-	"encoding/base64"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"github.com/bep/debounce"
 	"github.com/go-redis/redis/v9"
 	"github.com/gofiber/fiber/v2"
 	"github.com/meilisearch/meilisearch-go"
-	"strings"
 	"time"
 )
 
+// JobTimeout how long a single job may take before its upstream fetches are abandoned
+const JobTimeout = 30 * time.Second
+
 type UUIDPool struct {
 	PriorityJobs chan string
 	Jobs         chan string
@@ -27,13 +29,25 @@ func NewUUIDPool() *UUIDPool {
 	}
 }
 
-func handleJob(handler api.Handler, index *meilisearch.Index, limit chan struct{}, debounced func(f func()), increment func(), uuid string) {
+func handleJob(handler api.Handler, index *meilisearch.Index, limit chan struct{}, debounced func(f func()), increment func(), rawUUID string) {
 	defer func() {
 		<-limit
 	}()
 
+	//normalize the uuid so every variant of it indexes the same document
+	uuid, err := api.ParseUUID(rawUUID)
+
+	if err != nil {
+		handler.Logger.Error("Refusing bad uuid (%s): %v", rawUUID, err)
+		return
+	}
+
+	//bound the job, so a hanging upstream does not hold its slot of the limit forever
+	ctx, cancel := context.WithTimeout(handler.Ctx, JobTimeout)
+	defer cancel()
+
 	//fetch the profile based on the uuid from mojang
-	code, profile, _, errs := handler.FetchProfile(uuid)
+	code, profile, _, errs := handler.FetchProfile(ctx, uuid)
 
 	//rate limited by mojang api, slow down request speed
 	if code == fiber.StatusTooManyRequests {
@@ -57,21 +71,29 @@ func handleJob(handler api.Handler, index *meilisearch.Index, limit chan struct{
 
 	//successfully fetched profile from mojang
 	if code == fiber.StatusOK {
+		//refuse to index profiles whose signature does not match, without a public key they are indexed unverified
+		if err := handler.VerifyProfile(profile); errors.Is(err, yggdrasil.ErrNoKey) {
+			handler.Logger.Debug("Indexing profile %v (%s)", err, uuid)
+		} else if err != nil {
+			handler.Logger.Error("Refusing profile, %v (%s)", err, uuid)
+			return
+		}
+
 		//parse textures from profile.Properties
-		texturesDataBase64String := profile.Properties[0].Value
-		textureDataJsonString, _ := base64.StdEncoding.DecodeString(texturesDataBase64String)
+		textureResponse, err := profile.DecodeTextures()
 
-		textureResponse := &mojang.TextureResponse{}
-		_ = json.Unmarshal(textureDataJsonString, textureResponse)
+		if err != nil {
+			handler.Logger.Error("%v", err)
+			return
+		}
 
 		skinResponse := ""
 		capeResponse := ""
 
 		//fetch the textures from mojang
 		if textureResponse.Textures.Skin.Url != "" {
-			splitString := strings.Split(textureResponse.Textures.Skin.Url, "/")
-			textureid := splitString[len(splitString)-1]
-			_, skinResponse, _, errs = handler.FetchTexture(textureid)
+			textureid := api.TextureId(textureResponse.Textures.Skin.Url)
+			_, skinResponse, _, errs = handler.FetchTexture(ctx, textureid)
 
 			if len(errs) != 0 {
 				//TODO: Requeue for lookup again
@@ -84,9 +106,8 @@ func handleJob(handler api.Handler, index *meilisearch.Index, limit chan struct{
 		}
 
 		if textureResponse.Textures.Cape.Url != "" {
-			splitString := strings.Split(textureResponse.Textures.Cape.Url, "/")
-			textureid := splitString[len(splitString)-1]
-			_, capeResponse, _, errs = handler.FetchTexture(textureid)
+			textureid := api.TextureId(textureResponse.Textures.Cape.Url)
+			_, capeResponse, _, errs = handler.FetchTexture(ctx, textureid)
 
 			if len(errs) != 0 {
 				//TODO: Requeue for lookup again
@@ -100,7 +121,7 @@ func handleJob(handler api.Handler, index *meilisearch.Index, limit chan struct{
 
 		//populate doc with Textures
 		doc := mojang.Document{
-			Id:   profile.Id,
+			Id:   uuid,
 			Name: profile.Name,
 			Textures: mojang.Textures{
 				Skin: mojang.Skin{
@@ -113,12 +134,12 @@ func handleJob(handler api.Handler, index *meilisearch.Index, limit chan struct{
 		}
 
 		//check if the doc differs from redis
-		exists, item, _ := handler.CacheGet(fmt.Sprintf("scanner:%s", doc.Id))
+		exists, item, _ := handler.CacheGet(ctx, api.ScannerKey(doc.Id))
 
 		if !exists {
 			//item does not exist in cache, put into cache and meilisearch
 			docJsonString, _ := json.Marshal(&doc)
-			err := handler.CachePut(fmt.Sprintf("scanner:%s", doc.Id), string(docJsonString), 0)
+			err := handler.CachePut(ctx, api.ScannerKey(doc.Id), string(docJsonString), 0)
 
 			if err != nil {
 				handler.Logger.Error("%v", err)
@@ -145,7 +166,7 @@ func handleJob(handler api.Handler, index *meilisearch.Index, limit chan struct{
 			if doc.Name != foundDoc.Name || doc.Textures.Skin.Data != foundDoc.Textures.Skin.Data || doc.Textures.Cape.Data != foundDoc.Textures.Cape.Data {
 				//updating doc to cache and meilisearch, doc data differs from foundDoc
 				docJsonString, _ := json.Marshal(&doc)
-				err := handler.CachePut(fmt.Sprintf("scanner:%s", doc.Id), string(docJsonString), 0)
+				err := handler.CachePut(ctx, api.ScannerKey(doc.Id), string(docJsonString), 0)
 
 				if err != nil {
 					handler.Logger.Error("%v", err)
@@ -4,10 +4,12 @@ import (
 	"context"
 	"github.com/meilisearch/meilisearch-go"
 	"net"
+	"time"
 
 	"bed.gg/minecraft-api/v2/src/api"
 	"bed.gg/minecraft-api/v2/src/config"
 	"bed.gg/minecraft-api/v2/src/logger"
+	"bed.gg/minecraft-api/v2/src/yggdrasil"
 	"github.com/go-redis/redis/v9"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -36,9 +38,16 @@ func main() {
 	})
 
 	// -- connect to meilisearch --
+	readTimeout := api.DefaultReadTimeout
+
+	if config.Api.ReadTimeout > 0 {
+		readTimeout = time.Duration(config.Api.ReadTimeout) * time.Second
+	}
+
 	client := meilisearch.NewClient(meilisearch.ClientConfig{
-		Host:   "http://meilisearch:7700",
-		APIKey: MEILISEARCH_API_KEY,
+		Host:    "http://meilisearch:7700",
+		APIKey:  MEILISEARCH_API_KEY,
+		Timeout: readTimeout,
 	})
 
 	// -- setup the ip config --
@@ -49,16 +58,114 @@ func main() {
 		ipPool = append(ipPool, net.ParseIP(ip))
 	}
 
+	// -- schedule the source ips, skipping ips that used up their budget or got rate limited --
+	scheduler := api.NewIPScheduler(ipPool)
+
+	if config.Ip.Budget > 0 {
+		scheduler.Budget = config.Ip.Budget
+	} else if config.Ip.Budget < 0 {
+		scheduler.Budget = 0
+	}
+
+	if config.Ip.Window > 0 {
+		scheduler.Window = time.Duration(config.Ip.Window) * time.Second
+	}
+
+	if config.Ip.Cooldown > 0 {
+		scheduler.Cooldown = time.Duration(config.Ip.Cooldown) * time.Second
+	}
+
+	// -- load the yggdrasil public key --
+	keyCtx, cancelKey := context.WithTimeout(context.Background(), 10*time.Second)
+	verifier, err := yggdrasil.LoadVerifier(keyCtx, config.Api.YggdrasilPublicKey, config.Api.PublicKeysUrl)
+	cancelKey()
+
+	if err != nil {
+		lg.Warn("No yggdrasil public key loaded, profile signatures will not be verified: %v", err)
+	}
+
+	// -- coalesce concurrent upstream fetches, optionally across instances --
+	coalescer := api.NewCoalescer(nil)
+
+	if config.Api.DistributedLock && config.Api.Cache != "memory" {
+		coalescer = api.NewCoalescer(rdb)
+	}
+
 	// -- create the api handler --
 	handler := &api.Handler{
-		Logger:   lg,
-		Rdb:      rdb,
-		MSClient: client,
-		Ctx:      context.Background(),
-		IPPool:   ipPool,
-		IpIdx:    0,
+		Logger:    lg,
+		Rdb:       rdb,
+		MSClient:  client,
+		Ctx:       context.Background(),
+		IPPool:    ipPool,
+		IpIdx:     0,
+		Verifier:  verifier,
+		Scheduler: scheduler,
+		AdminKey:  config.Api.AdminKey,
+		Coalescer: coalescer,
 	}
 
+	// -- setup the cache backend --
+	switch config.Api.Cache {
+	case "memory":
+		lg.Warn("Caching in memory, entries are not shared with other instances")
+		cache := api.NewMemoryCache()
+		handler.Cache = cache
+
+		go func() {
+			for range time.Tick(time.Minute) {
+				cache.Sweep()
+			}
+		}()
+	case "", "redis":
+		cache := api.NewRedisCache(rdb)
+
+		// -- keep hot entries in process, dropping the ones other replicas overwrite --
+		if config.Api.LocalCacheSize >= 0 {
+			cache.Local = api.NewLocalCache(config.Api.LocalCacheSize)
+
+			if err := cache.Local.Subscribe(handler.Ctx, rdb); err != nil {
+				lg.Warn("Local cache disabled, cache invalidations can not be received: %v", err)
+				cache.Local = nil
+			}
+		}
+
+		handler.Cache = cache
+	default:
+		lg.Fatal("Unknown cache backend: %s", config.Api.Cache)
+	}
+
+	// -- bound the upstream fetches of batch requests --
+	handler.Limiter = api.NewFetchLimiter(config.Api.MaxConcurrentFetches)
+	handler.MaxBatchSize = config.Api.MaxBatchSize
+
+	if config.Api.BatchTimeout > 0 {
+		handler.BatchTimeout = time.Duration(config.Api.BatchTimeout) * time.Second
+	}
+
+	if config.Api.RequestTimeout > 0 {
+		handler.RequestTimeout = time.Duration(config.Api.RequestTimeout) * time.Second
+	}
+
+	if config.Api.NegativeTTL > 0 {
+		handler.NegativeTTL = time.Duration(config.Api.NegativeTTL) * time.Second
+	}
+
+	// -- point the handler at the configured upstream hosts --
+	mojang := api.NewMojangClient(handler, api.MojangHosts{
+		SessionServer: config.Api.SessionServerUrl,
+		API:           config.Api.MojangApiUrl,
+		Textures:      config.Api.TexturesUrl,
+	})
+
+	mojang.ReadTimeout = readTimeout
+
+	if config.Api.DialTimeout > 0 {
+		mojang.DialTimeout = time.Duration(config.Api.DialTimeout) * time.Second
+	}
+
+	handler.Mojang = mojang
+
 	// -- fiber app --
 	app := fiber.New()
 
@@ -68,12 +175,28 @@ func main() {
 		AllowHeaders: "Origin, Content-Type, Accept",
 	}))
 
+	//bound every request, abandoning the upstream work of requests that run out of time
+	app.Use(handler.RequestContext)
+
 	// -- register routes --
 	app.Get("/profile/:uuid", handler.GetProfile)
+	app.Get("/profile/name/:username", handler.GetUUID)
+	app.Get("/profile/:uuid/decoded", handler.GetDecodedProfile)
 	app.Get("/profiles", handler.GetProfiles)
+	app.Get("/texture/:textureid.png", handler.GetTexturePNG)
 	app.Get("/texture/:textureid", handler.GetTexture)
 	app.Get("/textures", handler.GetTextures)
+	app.Get("/uuid/offline/:username", handler.GetOfflineUUID)
+	app.Get("/uuid/format/:uuid", handler.GetUUIDFormat)
+	app.Get("/uuid/:username", handler.GetUUID)
+	app.Post("/uuids", handler.PostUUIDs)
+	app.Get("/render/3d/:uuid", handler.GetRender3D)
+	app.Get("/render/:type/:uuid", handler.GetRender)
+	app.Get("/server/bedrock/:host", handler.GetBedrockServer)
+	app.Get("/server/:host/icon.png", handler.GetServerIcon)
+	app.Get("/server/:host", handler.GetServer)
 	app.Get("/searchKey", handler.GetSearchKey)
+	app.Get("/admin/ips", handler.GetIPPool)
 
 	// -- start the server --
 	lg.Fatal("%s", app.Listen(":8080"))
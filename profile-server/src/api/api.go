@@ -4,17 +4,19 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/meilisearch/meilisearch-go"
 	"net"
 	"regexp"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"bed.gg/minecraft-api/v2/src/logger"
+	"bed.gg/minecraft-api/v2/src/yggdrasil"
 	"github.com/go-redis/redis/v9"
 	"github.com/gofiber/fiber/v2"
-	"github.com/google/uuid"
 	"github.com/valyala/fasthttp"
 )
 
@@ -22,9 +24,34 @@ type Handler struct {
 	Logger   *logger.ZapLogger
 	Rdb      *redis.Client
 	MSClient *meilisearch.Client
+	// Ctx the server context, requests derive theirs from it and background work outliving a request runs with it
 	Ctx      context.Context
 	IPPool   []net.IP
 	IpIdx    uint32
+	PingIdx  uint32
+	Verifier *yggdrasil.Verifier
+	// Mojang upstream client, defaults to the public mojang api when nil
+	Mojang MojangClient
+	// Scheduler picks the source ip of upstream requests, plain round robin through IPPool when nil
+	Scheduler *IPScheduler
+	// AdminKey guards the admin routes, they are disabled when empty
+	AdminKey string
+	// Coalescer merges concurrent fetches of the same profile or texture, every caller fetches for itself when nil
+	Coalescer *Coalescer
+	// NegativeTTL how long players, names and textures mojang reported missing are cached, DefaultNegativeTTL when 0
+	NegativeTTL time.Duration
+	// Cache holds profiles, usernames, textures and renders, a RedisCache on Rdb when nil
+	Cache Cache
+	// Limiter bounds the upstream fetches of batch requests across all requests, unbounded when nil
+	Limiter *FetchLimiter
+	// MaxBatchSize how many ids a batch request may ask for, DefaultMaxBatchSize when 0
+	MaxBatchSize int
+	// BatchTimeout how long a batch request may take before its outstanding fetches are abandoned, DefaultBatchTimeout when 0
+	BatchTimeout time.Duration
+	// RequestTimeout how long any request may take before the upstream work done for it is abandoned, DefaultRequestTimeout when 0
+	RequestTimeout time.Duration
+	// AllowPrivateServers permits pinging servers on loopback and private networks, which are refused by default
+	AllowPrivateServers bool
 }
 
 type ProfileResponse struct {
@@ -35,29 +62,22 @@ type ProfileResponse struct {
 		Value     string `json:"value"`
 		Signature string `json:"signature"`
 	} `json:"properties"`
+	Verified bool `json:"verified"`
 }
 
 type UsernameResponse struct {
 	Name         string `json:"name"`
 	Id           string `json:"id"`
-	Error        string `json:"error"`
-	ErrorMessage string `json:"errorMessage"`
+	Error        string `json:"error,omitempty"`
+	ErrorMessage string `json:"errorMessage,omitempty"`
 }
 
-type MultiProfileResponse struct {
-	Code    int
-	Profile *ProfileResponse
-	Body    []byte
-	Errs    []error
-	Id      string
-}
-
-type MultiTextureResponse struct {
-	Code          int
-	Base64Texture string
-	Body          []byte
-	Errs          []error
-	Id            string
+type MultiUsernameResponse struct {
+	Code      int
+	Usernames []*UsernameResponse
+	Body      []byte
+	Errs      []error
+	Names     []string
 }
 
 type TexturesBody struct {
@@ -68,9 +88,34 @@ type UUIDSBody struct {
 	UUIDS []string `json:"uuids"`
 }
 
+type UsernamesBody struct {
+	Usernames []string `json:"usernames"`
+}
+
+// fetchResult the raw outcome of an upstream request, shared by coalesced callers who each parse the body themselves
+type fetchResult struct {
+	code int
+	body []byte
+	errs []error
+}
+
+// abandoned reports if the fetch was ended by its context, which for coalesced callers may be the one of another request
+func (r *fetchResult) abandoned() bool {
+	for _, err := range r.errs {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// MojangBatchSize maximum number of usernames accepted by a single call to the mojang batch profiles endpoint
+const MojangBatchSize = 10
+
 // IsValidUUID helper method to check if the provided uuid is a valid minecraft uuid
 func IsValidUUID(u string) bool {
-	_, err := uuid.Parse(u)
+	_, err := ParseUUID(u)
 	return err == nil
 }
 
@@ -85,6 +130,17 @@ func isValidUsername(u string) bool {
 	return matched
 }
 
+// isValidServerAddress helper method to check if the provided server address is a hostname or ip with an optional port
+func isValidServerAddress(address string) bool {
+	matched, err := regexp.Match("^[a-zA-Z0-9.-]{1,253}(:[0-9]{1,5})?$", []byte(address))
+
+	if err != nil {
+		return false
+	}
+
+	return matched
+}
+
 // isValidTextureId helper method to check if the provided textureid is in a valid format
 func isValidTextureId(id string) bool {
 	matched, err := regexp.Match("^[a-fA-F0-9]+$", []byte(id))
@@ -96,26 +152,30 @@ func isValidTextureId(id string) bool {
 	return matched
 }
 
-func (h *Handler) fetchIP() (int, []byte, []error) {
+// IPInfoUrl echoes the address a request came from, used to check the IPPool dials out from every ip
+const IPInfoUrl = "https://ipinfo.io/json"
+
+// fetchIP asks url which address the request came from, dialing from the next ip in the IPPool
+func (h *Handler) fetchIP(url string) (int, []byte, []error) {
 	a := fiber.AcquireAgent()
 	req := a.Request()
 	req.Header.SetMethod(fiber.MethodGet)
-	req.SetRequestURI(fmt.Sprintf("https://ipinfo.io/json"))
+	req.SetRequestURI(url)
 
 	if err := a.Parse(); err != nil {
 		h.Logger.Error("%v", err)
 		return fiber.StatusInternalServerError, nil, []error{err}
 	}
 
-	if len(h.IPPool) > 0 {
+	if ip := h.nextIP(); ip != nil {
 		customDialer := fasthttp.TCPDialer{
 			Concurrency: 1000,
 			LocalAddr: &net.TCPAddr{
-				IP: h.IPPool[atomic.AddUint32(&h.IpIdx, 1)%uint32(len(h.IPPool))],
+				IP: ip,
 			},
 		}
 
-		h.Logger.Info("Dialing %s from %s", fmt.Sprintf("https://ipinfo.io/json"), customDialer.LocalAddr.String())
+		h.Logger.Info("Dialing %s from %s", url, customDialer.LocalAddr.String())
 
 		a.HostClient.Dial = func(addr string) (net.Conn, error) {
 			return customDialer.Dial(addr)
@@ -125,45 +185,65 @@ func (h *Handler) fetchIP() (int, []byte, []error) {
 	return a.Bytes()
 }
 
-// fetchMojang helper method to access mojang api
-func (h *Handler) fetchMojang(formatUrl string, args ...interface{}) (int, []byte, []error) {
-	a := fiber.AcquireAgent()
-	req := a.Request()
-	req.Header.SetMethod(fiber.MethodGet)
-	req.SetRequestURI(fmt.Sprintf(formatUrl, args...))
+// nextIP rotates through the IPPool, returns nil when no pool is configured
+func (h *Handler) nextIP() net.IP {
+	if h.Scheduler != nil {
+		return h.Scheduler.Next()
+	}
 
-	if err := a.Parse(); err != nil {
+	if len(h.IPPool) == 0 {
+		return nil
+	}
 
-		if h.Logger != nil {
-			h.Logger.Error("%v", err)
-		}
+	return h.IPPool[atomic.AddUint32(&h.IpIdx, 1)%uint32(len(h.IPPool))]
+}
 
-		return fiber.StatusInternalServerError, nil, []error{err}
+// pingIP rotates through the IPPool for server pings, which do not spend the mojang budget of the Scheduler
+// and are not held back by its cooldowns. Returns nil when no pool is configured
+func (h *Handler) pingIP() net.IP {
+	if len(h.IPPool) == 0 {
+		return nil
 	}
 
-	if len(h.IPPool) > 0 {
-		customDialer := fasthttp.TCPDialer{
-			Concurrency: 1000,
-			LocalAddr: &net.TCPAddr{
-				IP: h.IPPool[atomic.AddUint32(&h.IpIdx, 1)%uint32(len(h.IPPool))],
-			},
-		}
+	return h.IPPool[atomic.AddUint32(&h.PingIdx, 1)%uint32(len(h.IPPool))]
+}
+
+// rateLimited reports a 429 received on ip to the Scheduler, so it is skipped while cooling down
+func (h *Handler) rateLimited(ip net.IP, retryAfter time.Duration) {
+	if h.Scheduler != nil && ip != nil {
+		h.Scheduler.RateLimited(ip, retryAfter)
+	}
+}
+
+// FetchProfile fetches the profile json from mojang api and returns a ProfileResponse, giving up once ctx is done
+func (h *Handler) FetchProfile(ctx context.Context, playerUUID UUID) (int, *ProfileResponse, []byte, []error) {
+	key := profileKey(playerUUID)
 
-		if h.Logger != nil {
-			h.Logger.Info("Dialing %s from %s", fmt.Sprintf(formatUrl, args...), customDialer.LocalAddr.String())
+	//concurrent fetches of the same profile share a single upstream request
+	result := h.coalesce(ctx, key, func() (interface{}, bool) {
+		code, body, errs := h.mojang().Profile(ctx, playerUUID)
+		return &fetchResult{code, body, errs}, len(errs) == 0 && code == fiber.StatusOK
+	}, func() (interface{}, bool) {
+		entry, err := h.cacheGetEntry(ctx, key)
+
+		if err != nil || entry == nil || entry.age() >= TTL {
+			return nil, false
 		}
 
-		a.HostClient.Dial = func(addr string) (net.Conn, error) {
-			return customDialer.Dial(addr)
+		if entry.Missing {
+			return &fetchResult{fiber.StatusNotFound, nil, []error{}}, true
 		}
-	}
 
-	return a.Bytes()
-}
+		return &fetchResult{fiber.StatusOK, []byte(entry.Value), []error{}}, true
+	}).(*fetchResult)
 
-// FetchProfile fetches the profile json from mojang api and returns a ProfileResponse
-func (h *Handler) FetchProfile(playerUUID string) (int, *ProfileResponse, []byte, []error) {
-	code, body, errs := h.fetchMojang("https://sessionserver.mojang.com/session/minecraft/profile/%s?unsigned=false", playerUUID)
+	//the request sharing its fetch with us went away, so we fetch on our own
+	if result.abandoned() && ctx.Err() == nil {
+		code, body, errs := h.mojang().Profile(ctx, playerUUID)
+		result = &fetchResult{code, body, errs}
+	}
+
+	code, body, errs := result.code, result.body, result.errs
 
 	if len(errs) > 0 {
 		return code, nil, nil, errs
@@ -184,46 +264,40 @@ func (h *Handler) FetchProfile(playerUUID string) (int, *ProfileResponse, []byte
 	return code, profileResponse, body, []error{}
 }
 
-// FetchProfiles fetches multiple profile jsons concurrently from the mojang api and returns an array of MultiProfileResponse
-func (h *Handler) FetchProfiles(uuids []string) []*MultiProfileResponse {
-	responses := make(chan *MultiProfileResponse, len(uuids))
-
-	wg := &sync.WaitGroup{}
-	wg.Add(len(uuids))
-
-	for _, playerUUID := range uuids {
-		go func(playerUUID string, h *Handler, wg *sync.WaitGroup, responses chan *MultiProfileResponse) {
-			defer wg.Done()
-
-			code, profileResponse, body, errs := h.FetchProfile(playerUUID)
-
-			response := &MultiProfileResponse{
-				Code:    code,
-				Profile: profileResponse,
-				Body:    body,
-				Errs:    errs,
-				Id:      playerUUID,
-			}
+// VerifyProfile checks the signature of every property of the profile against the yggdrasil public key.
+// Returns yggdrasil.ErrNoKey when no key is loaded and an error wrapping yggdrasil.ErrInvalidSignature when a signature does not match
+func (h *Handler) VerifyProfile(profile *ProfileResponse) error {
+	if h.Verifier == nil {
+		return yggdrasil.ErrNoKey
+	}
 
-			responses <- response
+	if len(profile.Properties) == 0 {
+		return fmt.Errorf("%w: profile has no properties", yggdrasil.ErrInvalidSignature)
+	}
 
-		}(playerUUID, h, wg, responses)
+	for _, property := range profile.Properties {
+		if err := h.Verifier.Verify(property.Value, property.Signature); err != nil {
+			return err
+		}
 	}
 
-	wg.Wait()
-	close(responses)
+	return nil
+}
 
-	var arr []*MultiProfileResponse
-	for response := range responses {
-		arr = append(arr, response)
+// verified returns if the signature of the profile checks out, logging the profiles whose signature is invalid
+func (h *Handler) verified(profile *ProfileResponse) bool {
+	err := h.VerifyProfile(profile)
+
+	if errors.Is(err, yggdrasil.ErrInvalidSignature) {
+		h.Logger.Warn("[%s] Profile %v", profile.Id, err)
 	}
 
-	return arr
+	return err == nil
 }
 
-// FetchUUID fetches the username json from mojang api and returns a UsernameResponse
-func (h *Handler) FetchUUID(username string) (int, *UsernameResponse, []byte, []error) {
-	code, body, errs := h.fetchMojang("https://api.mojang.com/users/profiles/minecraft/%s", username)
+// FetchUUID fetches the username json from mojang api and returns a UsernameResponse, giving up once ctx is done
+func (h *Handler) FetchUUID(ctx context.Context, username string) (int, *UsernameResponse, []byte, []error) {
+	code, body, errs := h.mojang().UUID(ctx, username)
 
 	if len(errs) > 0 {
 		return code, nil, nil, errs
@@ -244,55 +318,124 @@ func (h *Handler) FetchUUID(username string) (int, *UsernameResponse, []byte, []
 	return code, usernameResponse, body, []error{}
 }
 
-// FetchTexture fetches the texture as a base64 string from mojang api
-func (h *Handler) FetchTexture(textureid string) (int, string, []byte, []error) {
-	code, body, errs := h.fetchMojang("https://textures.minecraft.net/texture/%s", textureid)
+// FetchUUIDBatch resolves up to MojangBatchSize usernames with a single call to the mojang batch profiles endpoint
+func (h *Handler) FetchUUIDBatch(ctx context.Context, usernames []string) (int, []*UsernameResponse, []byte, []error) {
+	if len(usernames) > MojangBatchSize {
+		return fiber.StatusBadRequest, nil, nil, []error{fmt.Errorf("at most %d usernames can be resolved per batch, got %d", MojangBatchSize, len(usernames))}
+	}
+
+	code, body, errs := h.mojang().UUIDs(ctx, usernames)
 
 	if len(errs) > 0 {
-		return code, "", nil, errs
+		return code, nil, nil, errs
 	}
 
 	if code != fiber.StatusOK {
-		return code, "", nil, errs
+		return code, nil, nil, errs
 	}
 
-	//encode the texture to base64 and return the encoded string
-	return code, base64.StdEncoding.EncodeToString(body), body, []error{}
+	//deserialize the body and return the UsernameResponses, unknown usernames are omitted by mojang
+	var usernameResponses []*UsernameResponse
+	err := json.Unmarshal(body, &usernameResponses)
+
+	if err != nil {
+		return code, nil, nil, []error{err}
+	}
+
+	return code, usernameResponses, body, []error{}
 }
 
-// FetchTextures fetches multiple textures concurrently from the mojang api and returns an array of MultiTextureResponse
-func (h *Handler) FetchTextures(textureids []string) []*MultiTextureResponse {
-	responses := make(chan *MultiTextureResponse, len(textureids))
+// FetchUUIDs resolves multiple usernames concurrently in batches of MojangBatchSize and returns an array of MultiUsernameResponse in batch order.
+// Each batch waits for a slot of the Limiter, the ones not started when ctx is done fail with 504
+func (h *Handler) FetchUUIDs(ctx context.Context, usernames []string) []*MultiUsernameResponse {
+	var batches [][]string
+	for start := 0; start < len(usernames); start += MojangBatchSize {
+		end := start + MojangBatchSize
+		if end > len(usernames) {
+			end = len(usernames)
+		}
+
+		batches = append(batches, usernames[start:end])
+	}
+
+	responses := make([]*MultiUsernameResponse, len(batches))
 
 	wg := &sync.WaitGroup{}
-	wg.Add(len(textureids))
+	wg.Add(len(batches))
 
-	for _, textureid := range textureids {
-		go func(textureid string, h *Handler, wg *sync.WaitGroup, responses chan *MultiTextureResponse) {
+	for i, batch := range batches {
+		go func(i int, batch []string) {
 			defer wg.Done()
 
-			code, base64Texture, body, errs := h.FetchTexture(textureid)
-
-			response := &MultiTextureResponse{
-				Code:          code,
-				Base64Texture: base64Texture,
-				Body:          body,
-				Errs:          errs,
-				Id:            textureid,
+			if err := h.Limiter.Acquire(ctx); err != nil {
+				responses[i] = &MultiUsernameResponse{Code: fiber.StatusGatewayTimeout, Errs: []error{err}, Names: batch}
+				return
 			}
+			defer h.Limiter.Release()
+
+			code, usernameResponses, body, errs := h.FetchUUIDBatch(ctx, batch)
 
-			responses <- response
+			response := &MultiUsernameResponse{
+				Code:      code,
+				Usernames: usernameResponses,
+				Body:      body,
+				Errs:      errs,
+				Names:     batch,
+			}
 
-		}(textureid, h, wg, responses)
+			responses[i] = response
+		}(i, batch)
 	}
 
 	wg.Wait()
-	close(responses)
 
-	var arr []*MultiTextureResponse
-	for response := range responses {
-		arr = append(arr, response)
+	return responses
+}
+
+// FetchTexture fetches the texture as a base64 string from mojang api, giving up once ctx is done
+func (h *Handler) FetchTexture(ctx context.Context, textureid string) (int, string, []byte, []error) {
+	key := textureKey(textureid)
+
+	//concurrent fetches of the same texture share a single upstream request
+	result := h.coalesce(ctx, key, func() (interface{}, bool) {
+		code, body, errs := h.mojang().Texture(ctx, textureid)
+		return &fetchResult{code, body, errs}, len(errs) == 0 && code == fiber.StatusOK
+	}, func() (interface{}, bool) {
+		entry, err := h.cacheGetEntry(ctx, key)
+
+		if err != nil || entry == nil || entry.age() >= TextureTTL {
+			return nil, false
+		}
+
+		if entry.Missing {
+			return &fetchResult{fiber.StatusNotFound, nil, []error{}}, true
+		}
+
+		body, err := base64.StdEncoding.DecodeString(entry.Value)
+
+		if err != nil {
+			return nil, false
+		}
+
+		return &fetchResult{fiber.StatusOK, body, []error{}}, true
+	}).(*fetchResult)
+
+	//the request sharing its fetch with us went away, so we fetch on our own
+	if result.abandoned() && ctx.Err() == nil {
+		code, body, errs := h.mojang().Texture(ctx, textureid)
+		result = &fetchResult{code, body, errs}
+	}
+
+	code, body, errs := result.code, result.body, result.errs
+
+	if len(errs) > 0 {
+		return code, "", nil, errs
 	}
 
-	return arr
+	if code != fiber.StatusOK {
+		return code, "", nil, errs
+	}
+
+	//encode the texture to base64 and return the encoded string
+	return code, base64.StdEncoding.EncodeToString(body), body, []error{}
 }
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+// BatchResponse body of the batch routes, every requested id is either in Results or in Errors, both in request order
+type BatchResponse struct {
+	Results *orderedMap `json:"results"`
+	Errors  *orderedMap `json:"errors"`
+}
+
+// BatchError why a single id of a batch could not be resolved
+type BatchError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// orderedMap a json object that keeps its keys in the order they were first set
+type orderedMap struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func newOrderedMap() *orderedMap {
+	return &orderedMap{values: make(map[string]interface{})}
+}
+
+// Set stores value under key, a key set again keeps its position
+func (m *orderedMap) Set(key string, value interface{}) {
+	if _, ok := m.values[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+
+	m.values[key] = value
+}
+
+func (m *orderedMap) Len() int {
+	return len(m.keys)
+}
+
+func (m *orderedMap) MarshalJSON() ([]byte, error) {
+	buf := &bytes.Buffer{}
+	buf.WriteByte('{')
+
+	for i, key := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+
+		v, err := json.Marshal(m.values[key])
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func newBatchResponse() *BatchResponse {
+	return &BatchResponse{
+		Results: newOrderedMap(),
+		Errors:  newOrderedMap(),
+	}
+}
+
+// batchError describes a failed lookup, failures without an error status of their own are reported as 500
+func batchError(code int, errs []error, format string, args ...interface{}) *BatchError {
+	if code < 400 || (len(errs) > 0 && code < 500) {
+		code = fiber.StatusInternalServerError
+	}
+
+	if code == fiber.StatusNotFound {
+		return &BatchError{code, fmt.Sprintf(format, args...)}
+	}
+
+	return &BatchError{code, utils.StatusMessage(code)}
+}
+
+// sendBatch responds with the keyed results and errors of a batch route, ids that failed are retried by the next request.
+// Without errors clients may reuse the response for the ttl of its results, counted from the oldest one
+func (h *Handler) sendBatch(c *fiber.Ctx, response *BatchResponse, oldest time.Duration, ttl time.Duration) error {
+	if response.Errors.Len() > 0 {
+		c.Set(fiber.HeaderCacheControl, "no-cache")
+	} else {
+		setCacheHeaders(c, oldest, ttl)
+	}
+
+	c.Status(fiber.StatusOK)
+	return c.JSON(response)
+}
+
+// isLegacyBatch reports if the client asked for the array form of a batch route, which fails as a whole on any error
+func isLegacyBatch(c *fiber.Ctx) (bool, error) {
+	legacy, err := strconv.ParseBool(c.Query("legacy", "false"))
+
+	if err != nil {
+		return false, fmt.Errorf("bad legacy: %s", c.Query("legacy"))
+	}
+
+	return legacy, nil
+}
@@ -1,12 +1,66 @@
 package api
 
 import (
-	"github.com/go-redis/redis/v9"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 )
 
-func (h *Handler) CachePut(key string, value string, ttl time.Duration) error {
-	err := h.Rdb.Set(h.Ctx, key, value, ttl).Err()
+// profileKey cache key of the profile of a player
+func profileKey(playerUUID UUID) string {
+	return fmt.Sprintf("profile:%s", playerUUID)
+}
+
+// textureKey cache key of a texture, texture ids are hex so they are cached under their lowercase form
+func textureKey(textureid string) string {
+	return fmt.Sprintf("texture:%s", strings.ToLower(textureid))
+}
+
+// usernameKey cache key of a username lookup, usernames are case-insensitive
+func usernameKey(username string) string {
+	return fmt.Sprintf("username:%s", strings.ToLower(username))
+}
+
+// ScannerKey cache key of the last search document the scanner indexed for a player
+func ScannerKey(playerUUID UUID) string {
+	return fmt.Sprintf("scanner:%s", playerUUID)
+}
+
+// Cache the key value store behind the api, a ttl of 0 keeps an entry until it is deleted
+type Cache interface {
+	// Get returns if key exists and its value
+	Get(ctx context.Context, key string) (bool, string, error)
+	// Put stores value under key for ttl
+	Put(ctx context.Context, key string, value string, ttl time.Duration) error
+	// MultiGet returns the values of the keys that exist
+	MultiGet(ctx context.Context, keys []string) (map[string]string, error)
+	// MultiPut stores every item for ttl
+	MultiPut(ctx context.Context, items map[string]string, ttl time.Duration) error
+	// Delete removes the keys
+	Delete(ctx context.Context, keys ...string) error
+	// TTL returns if key exists and how long until it expires, 0 when it never does
+	TTL(ctx context.Context, key string) (bool, time.Duration, error)
+}
+
+// cache returns the configured Cache, defaulting to the redis of the handler
+func (h *Handler) cache() Cache {
+	if h.Cache != nil {
+		return h.Cache
+	}
+
+	return NewRedisCache(h.Rdb)
+}
+
+func (h *Handler) CachePut(ctx context.Context, key string, value string, ttl time.Duration) error {
+	err := h.cache().Put(ctx, key, value, ttl)
+
+	if errors.Is(err, ErrInvalidation) {
+		//the item was cached, only the copies of the other replicas may be stale
+		h.Logger.Error("[%s] Cached item without invalidating other replicas: %v", key, err)
+		return nil
+	}
 
 	if err != nil {
 		h.Logger.Error("[%s] Failed to cache item: %v", key, err)
@@ -15,19 +69,50 @@ func (h *Handler) CachePut(key string, value string, ttl time.Duration) error {
 	return err
 }
 
-func (h *Handler) CacheGet(key string) (bool, string, error) {
-	item, err := h.Rdb.Get(h.Ctx, key).Result()
+func (h *Handler) CacheGet(ctx context.Context, key string) (bool, string, error) {
+	exists, item, err := h.cache().Get(ctx, key)
 
-	switch err {
-	case redis.Nil:
-		//key does not exist in cache
-		return false, "", nil
-	case nil:
-		//key does exist in cache
-		return true, item, nil
-	default:
-		//some redis error occurred during cache lookup
+	if err != nil {
+		//some cache error occurred during cache lookup
 		h.Logger.Error("%v", err)
 		return false, "", err
 	}
+
+	return exists, item, nil
+}
+
+// CacheMultiGet returns the values of the keys that exist in a single round trip
+func (h *Handler) CacheMultiGet(ctx context.Context, keys []string) (map[string]string, error) {
+	if len(keys) == 0 {
+		return map[string]string{}, nil
+	}
+
+	items, err := h.cache().MultiGet(ctx, keys)
+
+	if err != nil {
+		h.Logger.Error("%v", err)
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// CacheMultiPut stores every item for ttl in a single round trip
+func (h *Handler) CacheMultiPut(ctx context.Context, items map[string]string, ttl time.Duration) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	err := h.cache().MultiPut(ctx, items, ttl)
+
+	if errors.Is(err, ErrInvalidation) {
+		h.Logger.Error("Cached %d items without invalidating other replicas: %v", len(items), err)
+		return nil
+	}
+
+	if err != nil {
+		h.Logger.Error("Failed to cache %d items: %v", len(items), err)
+	}
+
+	return err
 }
@@ -0,0 +1,140 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v9"
+)
+
+func TestCacheBackends(t *testing.T) {
+	backends := map[string]func(t *testing.T) Cache{
+		"memory": func(t *testing.T) Cache {
+			return NewMemoryCache()
+		},
+		"redis": func(t *testing.T) Cache {
+			return NewRedisCache(redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()}))
+		},
+		"redis+local": func(t *testing.T) Cache {
+			cache := NewRedisCache(redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()}))
+			cache.Local = NewLocalCache(0)
+
+			return cache
+		},
+	}
+
+	for name, newCache := range backends {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			cache := newCache(t)
+
+			if exists, _, err := cache.Get(ctx, "profile:a"); exists || err != nil {
+				t.Fatalf("Get of missing key = %v %v", exists, err)
+			}
+
+			if err := cache.Put(ctx, "profile:a", "a", time.Hour); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := cache.Put(ctx, "scanner:b", "b", 0); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := cache.MultiPut(ctx, map[string]string{"texture:d": "d", "texture:e": "e"}, time.Hour); err != nil {
+				t.Fatal(err)
+			}
+
+			if exists, item, err := cache.Get(ctx, "texture:e"); !exists || item != "e" || err != nil {
+				t.Errorf("Get after MultiPut = %v %s %v, want e", exists, item, err)
+			}
+
+			if exists, item, err := cache.Get(ctx, "profile:a"); !exists || item != "a" || err != nil {
+				t.Errorf("Get = %v %s %v, want a", exists, item, err)
+			}
+
+			items, err := cache.MultiGet(ctx, []string{"profile:a", "scanner:b", "profile:c"})
+			if err != nil || len(items) != 2 || items["profile:a"] != "a" || items["scanner:b"] != "b" {
+				t.Errorf("MultiGet = %v %v", items, err)
+			}
+
+			if exists, ttl, err := cache.TTL(ctx, "profile:a"); !exists || ttl <= 0 || ttl > time.Hour || err != nil {
+				t.Errorf("TTL = %v %v %v, want at most an hour", exists, ttl, err)
+			}
+
+			if exists, ttl, err := cache.TTL(ctx, "scanner:b"); !exists || ttl != 0 || err != nil {
+				t.Errorf("TTL of key without expiry = %v %v %v", exists, ttl, err)
+			}
+
+			if err := cache.Delete(ctx, "profile:a", "scanner:b"); err != nil {
+				t.Fatal(err)
+			}
+
+			if exists, _, _ := cache.Get(ctx, "profile:a"); exists {
+				t.Error("profile:a survived Delete")
+			}
+
+			if exists, _, _ := cache.TTL(ctx, "scanner:b"); exists {
+				t.Error("scanner:b survived Delete")
+			}
+		})
+	}
+}
+
+func TestMemoryCacheExpiry(t *testing.T) {
+	ctx := context.Background()
+	cache := NewMemoryCache()
+
+	if err := cache.Put(ctx, "profile:a", "a", time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	cache.Sweep()
+
+	if exists, _, _ := cache.Get(ctx, "profile:a"); exists {
+		t.Error("profile:a outlived its ttl")
+	}
+
+	if len(cache.entries) != 0 {
+		t.Errorf("%d entries left after Sweep", len(cache.entries))
+	}
+}
+
+func TestLookupProfilesRoundTrips(t *testing.T) {
+	handler, server := newFakeHandler(t)
+
+	mr := miniredis.RunT(t)
+	handler.Rdb = redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	handler.Ctx = context.Background()
+
+	uuids := []UUID{UUID(jeb.Id), UUID(notch.Id), UUID(alex.Id)}
+
+	//connecting runs commands of its own
+	if err := handler.Rdb.Ping(handler.Ctx).Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	//misses are read with one MGET and written back with one pipeline of SETs
+	before := mr.CommandCount()
+	lookups := handler.lookupProfiles(handler.Ctx, uuids)
+
+	if mr.CommandCount()-before != 1+len(uuids) {
+		t.Errorf("cold lookup ran %d redis commands, want %d", mr.CommandCount()-before, 1+len(uuids))
+	}
+
+	for i, lookup := range lookups {
+		if lookup.profile == nil || UUID(lookup.profile.Id) != uuids[i] {
+			t.Fatalf("lookups[%d] = %+v, want %s", i, lookup.profile, uuids[i])
+		}
+	}
+
+	//hits are read with a single MGET and never reach the upstream
+	before, requests := mr.CommandCount(), server.Requests()
+	handler.lookupProfiles(handler.Ctx, uuids)
+
+	if mr.CommandCount()-before != 1 || server.Requests() != requests {
+		t.Errorf("warm lookup ran %d redis commands and %d upstream requests, want 1 and 0", mr.CommandCount()-before, server.Requests()-requests)
+	}
+}
@@ -0,0 +1,167 @@
+package api
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v9"
+	"github.com/google/uuid"
+)
+
+// DefaultLockTTL how long an instance holds the redis lock of a key, and how long other instances wait for it to fill the cache
+const DefaultLockTTL = 3 * time.Second
+
+// lockPollInterval how often instances waiting on the redis lock of another instance check the cache
+const lockPollInterval = 25 * time.Millisecond
+
+// unlockTimeout bounds releasing redis locks, which does not depend on the request that took them
+const unlockTimeout = time.Second
+
+// unlockScript deletes a lock only while it still holds the given token, checking and deleting in one atomic step so
+// a lock that expired and was taken by another instance in between is left alone
+var unlockScript = redis.NewScript(`if redis.call("get", KEYS[1]) == ARGV[1] then return redis.call("del", KEYS[1]) end return 0`)
+
+// Coalescer merges concurrent upstream fetches of the same key into a single fetch whose result every caller receives
+type Coalescer struct {
+	// Rdb optional redis used to take a short lock per key, so only one instance fetches a key at a time
+	Rdb *redis.Client
+	// LockTTL how long the redis lock is held at most
+	LockTTL time.Duration
+
+	mu    sync.Mutex
+	calls map[string]*call
+	token string
+}
+
+// call a fetch in flight and the callers waiting on it
+type call struct {
+	wg  sync.WaitGroup
+	val interface{}
+}
+
+// NewCoalescer creates a Coalescer, locking keys across instances when rdb is not nil
+func NewCoalescer(rdb *redis.Client) *Coalescer {
+	return &Coalescer{
+		Rdb:     rdb,
+		LockTTL: DefaultLockTTL,
+		calls:   make(map[string]*call),
+		token:   uuid.NewString(),
+	}
+}
+
+// Do runs fn once for all concurrent callers of the same key and hands each of them its result
+func (c *Coalescer) Do(key string, fn func() interface{}) interface{} {
+	c.mu.Lock()
+
+	if existing, ok := c.calls[key]; ok {
+		c.mu.Unlock()
+		existing.wg.Wait()
+
+		return existing.val
+	}
+
+	cl := &call{}
+	cl.wg.Add(1)
+	c.calls[key] = cl
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.calls, key)
+		c.mu.Unlock()
+
+		cl.wg.Done()
+	}()
+
+	cl.val = fn()
+	return cl.val
+}
+
+// lock takes the redis lock of key, reporting true when this instance should fetch it.
+// Without a redis, or when redis fails, every instance fetches for itself
+func (c *Coalescer) lock(ctx context.Context, key string) bool {
+	if c.Rdb == nil {
+		return true
+	}
+
+	acquired, err := c.Rdb.SetNX(ctx, lockKey(key), c.token, c.LockTTL).Result()
+
+	return err != nil || acquired
+}
+
+// unlock releases the redis locks of the keys this instance still holds in a single round trip.
+// The request that took a lock may be gone by now, so it runs with a short context of its own
+func (c *Coalescer) unlock(keys ...string) {
+	if c.Rdb == nil || len(keys) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), unlockTimeout)
+	defer cancel()
+
+	pipe := c.Rdb.Pipeline()
+	for _, key := range keys {
+		unlockScript.Eval(ctx, pipe, []string{lockKey(key)}, c.token)
+	}
+
+	_, _ = pipe.Exec(ctx)
+}
+
+// releaseLocks releases the redis locks taken to fetch keys once what was fetched is cached,
+// so instances waiting on them read the cache instead of waiting for the locks to expire
+func (h *Handler) releaseLocks(keys ...string) {
+	if h.Coalescer != nil {
+		h.Coalescer.unlock(keys...)
+	}
+}
+
+func lockKey(key string) string {
+	return "lock:" + key
+}
+
+// coalesce fetches key once for all concurrent callers. With a redis lock configured, instances losing the lock
+// wait up to LockTTL for the winner to fill the cache and read it through cached instead of fetching themselves,
+// taking over as soon as the winner releases the lock without having filled it.
+// A successful fetch keeps the lock until the caller cached it and called releaseLocks.
+// The shared fetch runs with the ctx of the first caller
+func (h *Handler) coalesce(ctx context.Context, key string, fetch func() (interface{}, bool), cached func() (interface{}, bool)) interface{} {
+	if h.Coalescer == nil {
+		val, _ := fetch()
+		return val
+	}
+
+	c := h.Coalescer
+
+	return c.Do(key, func() interface{} {
+		if !c.lock(ctx, key) {
+			deadline := time.Now().Add(c.LockTTL)
+
+			for time.Now().Before(deadline) && ctx.Err() == nil {
+				time.Sleep(lockPollInterval)
+
+				if val, ok := cached(); ok {
+					return val
+				}
+
+				if c.lock(ctx, key) {
+					//the winner caches before releasing, so a value stored in between is read instead of fetched again
+					if val, ok := cached(); ok {
+						c.unlock(key)
+						return val
+					}
+
+					break
+				}
+			}
+		}
+
+		val, ok := fetch()
+
+		if !ok {
+			c.unlock(key)
+		}
+
+		return val
+	})
+}
@@ -0,0 +1,171 @@
+package api
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v9"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestCoalesceFetches(t *testing.T) {
+	handler, server := newFakeHandler(t)
+	handler.Coalescer = NewCoalescer(nil)
+	server.SetLatency(100 * time.Millisecond)
+
+	wg := &sync.WaitGroup{}
+
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+
+		go func() {
+			defer wg.Done()
+
+			code, profile, _, errs := handler.FetchProfile(context.Background(), UUID(notch.Id))
+			if code != fiber.StatusOK || profile == nil || len(errs) > 0 {
+				t.Errorf("FetchProfile = %d %v", code, errs)
+			}
+		}()
+
+		go func() {
+			defer wg.Done()
+
+			code, texture, _, errs := handler.FetchTexture(context.Background(), server.SkinTextureId(notch.Id))
+			if code != fiber.StatusOK || texture == "" || len(errs) > 0 {
+				t.Errorf("FetchTexture = %d %v", code, errs)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if server.Requests() != 2 {
+		t.Errorf("fake api served %d requests, want 2", server.Requests())
+	}
+}
+
+func TestCoalesceAcrossInstances(t *testing.T) {
+	first, server := newFakeHandler(t)
+	server.SetLatency(100 * time.Millisecond)
+
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+
+	//a second instance sharing the redis and the upstream, but not the process
+	second := &Handler{Logger: first.Logger, Mojang: first.Mojang}
+
+	for _, h := range []*Handler{first, second} {
+		h.Rdb = rdb
+		h.Ctx = context.Background()
+		h.Coalescer = NewCoalescer(rdb)
+	}
+
+	wg := &sync.WaitGroup{}
+	wg.Add(2)
+
+	for _, h := range []*Handler{first, second} {
+		go func(h *Handler) {
+			defer wg.Done()
+
+			code, profile, _, _, errs := h.lookupProfile(context.Background(), UUID(jeb.Id))
+			if code != fiber.StatusOK || profile == nil || profile.Name != jeb.Name {
+				t.Errorf("lookupProfile = %d %v", code, errs)
+			}
+		}(h)
+	}
+
+	wg.Wait()
+
+	if server.Requests() != 1 {
+		t.Errorf("fake api served %d requests, want 1", server.Requests())
+	}
+
+	//the lock is released once the profile is cached, not left to expire
+	if mr.Exists(lockKey(profileKey(UUID(jeb.Id)))) {
+		t.Error("lock outlived the cache write")
+	}
+
+	//batch lookups release their locks after their cache write too
+	lookups := first.lookupProfiles(context.Background(), []UUID{UUID(notch.Id), UUID(alex.Id)})
+
+	for i, lookup := range lookups {
+		if lookup.code != fiber.StatusOK {
+			t.Errorf("lookupProfiles %d = %d %v", i, lookup.code, lookup.errs)
+		}
+	}
+
+	if keys := mr.Keys(); len(keys) != 3 {
+		t.Errorf("redis keys after batch lookup = %v, want only the cached profiles", keys)
+	}
+}
+
+func TestCoalesceTakeOver(t *testing.T) {
+	handler, server := newFakeHandler(t)
+
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	handler.Rdb = rdb
+	handler.Coalescer = NewCoalescer(rdb)
+	handler.Coalescer.LockTTL = 2 * time.Second
+
+	//another instance holds the lock, then releases it without caching anything, as after a failed fetch
+	other := NewCoalescer(rdb)
+	key := profileKey(UUID(notch.Id))
+
+	if !other.lock(context.Background(), key) {
+		t.Fatal("lock was not taken")
+	}
+
+	time.AfterFunc(100*time.Millisecond, func() {
+		other.unlock(key)
+	})
+
+	start := time.Now()
+
+	code, _, _, errs := handler.FetchProfile(context.Background(), UUID(notch.Id))
+	if code != fiber.StatusOK || len(errs) > 0 {
+		t.Fatalf("FetchProfile = %d %v", code, errs)
+	}
+
+	//the waiting instance fetched as soon as the lock was free, not once it expired
+	if elapsed := time.Since(start); elapsed >= handler.Coalescer.LockTTL {
+		t.Errorf("FetchProfile took %v, want less than the lock ttl", elapsed)
+	}
+
+	if server.Requests() != 1 {
+		t.Errorf("fake api served %d requests, want 1", server.Requests())
+	}
+}
+
+func TestCoalescerUnlock(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	ctx := context.Background()
+
+	c := NewCoalescer(rdb)
+	other := NewCoalescer(rdb)
+
+	if !c.lock(ctx, "profile:a") || other.lock(ctx, "profile:a") {
+		t.Fatal("lock was not exclusive")
+	}
+
+	c.unlock("profile:a")
+
+	if mr.Exists(lockKey("profile:a")) {
+		t.Fatal("unlock left the lock of its own instance")
+	}
+
+	//a lock that expired and was taken by another instance is not released by the previous holder
+	if !other.lock(ctx, "profile:a") {
+		t.Fatal("lock was not released")
+	}
+
+	c.unlock("profile:a")
+
+	if holder, _ := mr.Get(lockKey("profile:a")); holder != other.token {
+		t.Errorf("lock holder after a foreign unlock = %s, want %s", holder, other.token)
+	}
+}
@@ -1,41 +1,50 @@
 package api
 
 import (
-	"bed.gg/minecraft-api/v2/src/logger"
 	"context"
-	"github.com/go-redis/redis/v9"
-	"github.com/gofiber/fiber/v2"
+	"encoding/json"
+	"errors"
 	"net"
 	"sync"
 	"testing"
+	"time"
+
+	"bed.gg/minecraft-api/v2/src/logger"
+	"bed.gg/minecraft-api/v2/src/mojangtest"
+	"bed.gg/minecraft-api/v2/src/yggdrasil"
+	"github.com/gofiber/fiber/v2"
 )
 
-func TestFetchTimeout(t *testing.T) {
-	// -- create a new logger --
-	lg := logger.NewLogger()
-
-	// -- connect to redis --
-	rdb := redis.NewClient(&redis.Options{
-		Addr:     "localhost:6379",
-		Password: "", // no password set
-		DB:       0,  // use default DB
-	})
+var (
+	notch = mojangtest.Player{Id: "069a79f444e94726a5befca90e38aaf5", Name: "Notch", Cape: true}
+	jeb   = mojangtest.Player{Id: "853c80ef3c3749fdaa49938b674adae6", Name: "jeb_", Slim: true}
+	alex  = mojangtest.Player{Id: "9032ea59caa14489a167c19a32f9771d", Name: "Alex", DefaultSkin: true}
+)
+
+// newFakeHandler creates a handler talking to a fake mojang api seeded with a few players
+func newFakeHandler(t *testing.T) (*Handler, *mojangtest.Server) {
+	server := mojangtest.NewServer(notch, jeb, alex)
+	t.Cleanup(server.Close)
 
-	// -- create the api handler --
 	handler := &Handler{
-		Logger: lg,
-		Rdb:    rdb,
-		Ctx:    context.Background(),
-		IPPool: []net.IP{
-			net.ParseIP("10.0.0.4"),
-			net.ParseIP("10.0.0.5"),
-			net.ParseIP("10.0.0.6"),
-			net.ParseIP("10.0.0.7"),
-			net.ParseIP("10.0.0.8"),
-		},
-		IpIdx: 0,
+		Logger:   logger.NewLogger(),
+		Ctx:      context.Background(),
+		Verifier: &yggdrasil.Verifier{Key: &server.Key.PublicKey},
 	}
 
+	handler.Mojang = NewMojangClient(handler, MojangHosts{
+		SessionServer: server.URL,
+		API:           server.URL,
+		Textures:      server.URL,
+	})
+
+	return handler, server
+}
+
+func TestFetchTimeout(t *testing.T) {
+	handler, server := newFakeHandler(t)
+	server.SetLatency(50 * time.Millisecond)
+
 	limit := 202
 
 	wg := &sync.WaitGroup{}
@@ -45,18 +54,17 @@ func TestFetchTimeout(t *testing.T) {
 	for i := 0; i < limit; i++ {
 		go func(wg *sync.WaitGroup) {
 			defer wg.Done()
-			code, _, body, errs := handler.FetchProfile("9032ea59caa14489a167c19a32f9771d")
+			code, profile, body, errs := handler.FetchProfile(context.Background(), "9032ea59caa14489a167c19a32f9771d")
 
-			if code != fiber.StatusOK {
+			if code != fiber.StatusOK || len(errs) > 0 {
 				t.Error(errs)
 				t.Error(code)
 				t.Error(body)
+				return
 			}
 
-			if len(errs) > 0 {
-				t.Error(errs)
-				t.Error(code)
-				t.Error(body)
+			if err := handler.VerifyProfile(profile); err != nil {
+				t.Errorf("profile signature of %s did not verify: %v", profile.Name, err)
 			}
 		}(wg)
 	}
@@ -64,58 +72,242 @@ func TestFetchTimeout(t *testing.T) {
 	wg.Wait()
 }
 
+func TestVerifyProfile(t *testing.T) {
+	handler, _ := newFakeHandler(t)
+
+	_, profile, _, errs := handler.FetchProfile(context.Background(), UUID(notch.Id))
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	if err := handler.VerifyProfile(profile); err != nil {
+		t.Fatalf("VerifyProfile = %v", err)
+	}
+
+	//a tampered property is told apart from a profile that could not be checked
+	profile.Properties[0].Value += "="
+
+	if err := handler.VerifyProfile(profile); !errors.Is(err, yggdrasil.ErrInvalidSignature) {
+		t.Errorf("VerifyProfile of a tampered profile = %v, want ErrInvalidSignature", err)
+	}
+
+	handler.Verifier = nil
+
+	if err := handler.VerifyProfile(profile); err != yggdrasil.ErrNoKey {
+		t.Errorf("VerifyProfile without key = %v, want ErrNoKey", err)
+	}
+}
+
 func TestFetchIP(t *testing.T) {
-	// -- create a new logger --
-	lg := logger.NewLogger()
-
-	// -- connect to redis --
-	rdb := redis.NewClient(&redis.Options{
-		Addr:     "localhost:6379",
-		Password: "", // no password set
-		DB:       0,  // use default DB
-	})
+	handler, server := newFakeHandler(t)
 
-	// -- create the api handler --
-	handler := &Handler{
-		Logger: lg,
-		Rdb:    rdb,
-		Ctx:    context.Background(),
-		IPPool: []net.IP{
-			net.ParseIP("10.0.0.4"),
-			net.ParseIP("10.0.0.5"),
-			net.ParseIP("10.0.0.6"),
-			net.ParseIP("10.0.0.7"),
-			net.ParseIP("10.0.0.8"),
-		},
-		IpIdx: 0,
+	//every address of the loopback range is local, so the pool can be dialed from without extra interfaces
+	handler.IPPool = []net.IP{
+		net.ParseIP("127.0.0.2"),
+		net.ParseIP("127.0.0.3"),
+		net.ParseIP("127.0.0.4"),
 	}
 
-	limit := 8
+	seen := make(map[string]bool)
 
-	wg := &sync.WaitGroup{}
+	for i := 0; i < len(handler.IPPool)*2; i++ {
+		code, body, errs := handler.fetchIP(server.URL + "/json")
 
-	wg.Add(limit)
+		if code != fiber.StatusOK || len(errs) > 0 {
+			t.Fatal(code, errs, string(body))
+		}
 
-	for i := 0; i < limit; i++ {
-		go func(wg *sync.WaitGroup) {
-			defer wg.Done()
-			code, body, errs := handler.fetchIP()
+		ipResponse := struct {
+			Ip string `json:"ip"`
+		}{}
 
-			println(string(body))
+		if err := json.Unmarshal(body, &ipResponse); err != nil {
+			t.Fatal(err)
+		}
 
-			if code != fiber.StatusOK {
-				t.Error(errs)
-				t.Error(code)
-				t.Error(body)
-			}
+		seen[ipResponse.Ip] = true
+	}
 
-			if len(errs) > 0 {
-				t.Error(errs)
-				t.Error(code)
-				t.Error(body)
-			}
-		}(wg)
+	for _, ip := range handler.IPPool {
+		if !seen[ip.String()] {
+			t.Errorf("no request was dialed from %s, saw %v", ip, seen)
+		}
 	}
+}
 
-	wg.Wait()
+func TestFetchErrors(t *testing.T) {
+	handler, server := newFakeHandler(t)
+	handler.Mojang.(*FasthttpMojangClient).Retry = NoRetryPolicy
+
+	//unknown players are answered with an empty 204 by the session server
+	code, profile, _, _ := handler.FetchProfile(context.Background(), "00000000000000000000000000000000")
+	if code != fiber.StatusNoContent || profile != nil {
+		t.Errorf("FetchProfile unknown = %d %v, want 204", code, profile)
+	}
+
+	code, username, _, _ := handler.FetchUUID(context.Background(), "nobody")
+	if code != fiber.StatusNotFound || username != nil {
+		t.Errorf("FetchUUID unknown = %d %v, want 404", code, username)
+	}
+
+	server.RateLimit(2)
+
+	for i := 0; i < 2; i++ {
+		code, _, _, _ = handler.FetchUUID(context.Background(), "Notch")
+		if code != fiber.StatusTooManyRequests {
+			t.Errorf("FetchUUID during burst = %d, want 429", code)
+		}
+	}
+
+	code, username, _, errs := handler.FetchUUID(context.Background(), "notch")
+	if code != fiber.StatusOK || username.Id != notch.Id || len(errs) > 0 {
+		t.Errorf("FetchUUID after burst = %d %v %v", code, username, errs)
+	}
+}
+
+func TestFetchRetry(t *testing.T) {
+	handler, server := newFakeHandler(t)
+	client := handler.Mojang.(*FasthttpMojangClient)
+	client.Retry.BaseDelay = time.Millisecond
+
+	handler.IPPool = []net.IP{
+		net.ParseIP("127.0.0.2"),
+		net.ParseIP("127.0.0.3"),
+	}
+
+	//a short outage is hidden by the retries
+	server.Fail(2, fiber.StatusBadGateway)
+
+	code, profile, _, errs := handler.FetchProfile(context.Background(), UUID(notch.Id))
+	if code != fiber.StatusOK || profile == nil || len(errs) > 0 {
+		t.Fatalf("FetchProfile after outage = %d %v", code, errs)
+	}
+
+	//an outage outlasting the attempts surfaces the upstream status
+	server.Fail(client.Retry.MaxAttempts, fiber.StatusServiceUnavailable)
+
+	code, _, _, _ = handler.FetchProfile(context.Background(), UUID(notch.Id))
+	if code != fiber.StatusServiceUnavailable {
+		t.Errorf("FetchProfile during outage = %d, want 503", code)
+	}
+
+	//the Retry-After of a rate limit is honored, unless it is longer than MaxRetryAfter
+	server.SetRetryAfter(1)
+	server.RateLimit(1)
+
+	start := time.Now()
+	code, _, _, _ = handler.FetchUUID(context.Background(), "Notch")
+
+	if code != fiber.StatusOK || time.Since(start) < time.Second {
+		t.Errorf("FetchUUID after Retry-After = %d in %v", code, time.Since(start))
+	}
+
+	client.Retry.MaxRetryAfter = 500 * time.Millisecond
+	server.RateLimit(1)
+
+	code, _, _, _ = handler.FetchUUID(context.Background(), "Notch")
+	if code != fiber.StatusTooManyRequests {
+		t.Errorf("FetchUUID with a long Retry-After = %d, want 429", code)
+	}
+
+	//client errors are not retried
+	requests := server.Requests()
+	handler.FetchUUID(context.Background(), "nobody")
+
+	if server.Requests()-requests != 1 {
+		t.Errorf("404 was attempted %d times, want 1", server.Requests()-requests)
+	}
+}
+
+func TestFetchContext(t *testing.T) {
+	handler, server := newFakeHandler(t)
+	client := handler.Mojang.(*FasthttpMojangClient)
+
+	//a response slower than the ReadTimeout is a gateway timeout
+	client.ReadTimeout = 50 * time.Millisecond
+	client.Retry = NoRetryPolicy
+	server.SetLatency(200 * time.Millisecond)
+
+	code, profile, _, _ := handler.FetchProfile(context.Background(), UUID(notch.Id))
+	if code != fiber.StatusGatewayTimeout || profile != nil {
+		t.Errorf("FetchProfile past the ReadTimeout = %d %v, want 504", code, profile)
+	}
+
+	//a done context gives up on the response and the retries still to come
+	client.ReadTimeout = DefaultReadTimeout
+	client.Retry = DefaultRetryPolicy
+	client.Retry.BaseDelay = time.Second
+	server.SetLatency(0)
+	server.Fail(client.Retry.MaxAttempts, fiber.StatusServiceUnavailable)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	requests := server.Requests()
+	start := time.Now()
+	code, _, _, _ = handler.FetchProfile(ctx, UUID(jeb.Id))
+
+	if elapsed := time.Since(start); code != fiber.StatusServiceUnavailable || elapsed > 500*time.Millisecond {
+		t.Errorf("FetchProfile with a deadline = %d in %v, want the 503 without waiting for a retry", code, elapsed)
+	}
+
+	if server.Requests()-requests != 1 {
+		t.Errorf("FetchProfile with a deadline made %d upstream requests, want 1", server.Requests()-requests)
+	}
+
+	//a cancelled context does not reach the upstream at all
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+
+	requests = server.Requests()
+	code, _, _, _ = handler.FetchUUID(ctx, "Notch")
+
+	if code != fiber.StatusServiceUnavailable || server.Requests() != requests {
+		t.Errorf("FetchUUID cancelled = %d after %d upstream requests, want 503 and none", code, server.Requests()-requests)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	for value, want := range map[string]time.Duration{
+		"":                              0,
+		"3":                             3 * time.Second,
+		"-1":                            0,
+		"Sat, 01 Oct 2022 12:00:10 GMT": 10 * time.Second,
+		"Sat, 01 Oct 2022 11:00:00 GMT": 0,
+		"soon":                          0,
+	} {
+		if got := parseRetryAfter(value, now); got != want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", value, got, want)
+		}
+	}
+}
+
+func TestFetchUUIDs(t *testing.T) {
+	handler, _ := newFakeHandler(t)
+
+	usernames := []string{"Notch", "jeb_", "Alex", "nobody"}
+	for i := 0; i < MojangBatchSize; i++ {
+		usernames = append(usernames, "Notch")
+	}
+
+	found := 0
+	responses := handler.FetchUUIDs(context.Background(), usernames)
+
+	if len(responses) != 2 {
+		t.Fatalf("FetchUUIDs made %d batches, want 2", len(responses))
+	}
+
+	for _, response := range responses {
+		if response.Code != fiber.StatusOK || len(response.Errs) > 0 {
+			t.Fatal(response.Code, response.Errs)
+		}
+
+		found += len(response.Usernames)
+	}
+
+	if found != len(usernames)-1 {
+		t.Errorf("FetchUUIDs found %d usernames, want %d", found, len(usernames)-1)
+	}
 }
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// DefaultMaxBatchSize how many ids a single batch request may ask for by default
+const DefaultMaxBatchSize = 200
+
+// DefaultBatchTimeout how long a batch request may take by default before its outstanding fetches are abandoned
+const DefaultBatchTimeout = 10 * time.Second
+
+// DefaultRequestTimeout how long a request may take by default before the upstream work done for it is abandoned
+const DefaultRequestTimeout = 15 * time.Second
+
+// DefaultMaxConcurrentFetches how many upstream fetches of batch requests run at once by default
+const DefaultMaxConcurrentFetches = 64
+
+// FetchLimiter bounds how many upstream fetches of batch requests run at once, shared by every request in flight.
+// A nil FetchLimiter does not limit anything
+type FetchLimiter struct {
+	slots chan struct{}
+}
+
+// NewFetchLimiter creates a FetchLimiter running at most n fetches at once, DefaultMaxConcurrentFetches when 0
+func NewFetchLimiter(n int) *FetchLimiter {
+	if n <= 0 {
+		n = DefaultMaxConcurrentFetches
+	}
+
+	return &FetchLimiter{
+		slots: make(chan struct{}, n),
+	}
+}
+
+// Acquire waits for a free slot, giving up when ctx is done before one frees up
+func (l *FetchLimiter) Acquire(ctx context.Context) error {
+	if l == nil {
+		return ctx.Err()
+	}
+
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Release frees a slot taken by Acquire
+func (l *FetchLimiter) Release() {
+	if l == nil {
+		return
+	}
+
+	<-l.slots
+}
+
+// InFlight returns how many fetches hold a slot
+func (l *FetchLimiter) InFlight() int {
+	if l == nil {
+		return 0
+	}
+
+	return len(l.slots)
+}
+
+func (h *Handler) maxBatchSize() int {
+	if h.MaxBatchSize > 0 {
+		return h.MaxBatchSize
+	}
+
+	return DefaultMaxBatchSize
+}
+
+// batchContext bounds a batch request with context ctx by the BatchTimeout, DefaultBatchTimeout when 0
+func (h *Handler) batchContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	timeout := h.BatchTimeout
+
+	if timeout <= 0 {
+		timeout = DefaultBatchTimeout
+	}
+
+	return context.WithTimeout(ctx, timeout)
+}
+
+// checkBatchSize rejects batches of more than MaxBatchSize ids with 413
+func (h *Handler) checkBatchSize(n int) error {
+	if n > h.maxBatchSize() {
+		return fiber.NewError(fiber.StatusRequestEntityTooLarge, fmt.Sprintf("at most %d ids can be requested at once, got %d", h.maxBatchSize(), n))
+	}
+
+	return nil
+}
+
+// limitFetch runs fetch once the Limiter has a free slot, failing with 504 when ctx is done first
+func (h *Handler) limitFetch(ctx context.Context, key string, fetch func() (int, string, []error)) (int, string, []error) {
+	if err := h.Limiter.Acquire(ctx); err != nil {
+		h.Logger.Error("[%s] Abandoned fetch: %v", key, err)
+		return fiber.StatusGatewayTimeout, "", []error{}
+	}
+
+	defer h.Limiter.Release()
+
+	return fetch()
+}
+
+// RequestContext middleware giving every request a context bounded by the RequestTimeout, DefaultRequestTimeout when 0.
+// It derives from the server context Ctx and ends with the request, the routes read it through UserContext.
+// fasthttp does not report clients that hang up, so the upstream work of an aborted request runs until the timeout or shutdown
+func (h *Handler) RequestContext(c *fiber.Ctx) error {
+	timeout := h.RequestTimeout
+
+	if timeout <= 0 {
+		timeout = DefaultRequestTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(h.Ctx, timeout)
+	defer cancel()
+
+	c.SetUserContext(ctx)
+	return c.Next()
+}
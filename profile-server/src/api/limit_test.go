@@ -0,0 +1,104 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestFetchLimiter(t *testing.T) {
+	handler, server := newFakeHandler(t)
+	handler.Cache = NewMemoryCache()
+	handler.Ctx = context.Background()
+	handler.Limiter = NewFetchLimiter(1)
+	server.SetLatency(30 * time.Millisecond)
+
+	//a single slot runs the fetches one after another
+	start := time.Now()
+	lookups := handler.lookupProfiles(handler.Ctx, []UUID{UUID(notch.Id), UUID(jeb.Id), UUID(alex.Id)})
+
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("3 fetches through 1 slot took %v, want at least 90ms", elapsed)
+	}
+
+	for i, lookup := range lookups {
+		if lookup.code != fiber.StatusOK {
+			t.Errorf("lookups[%d] = %d %v", i, lookup.code, lookup.errs)
+		}
+	}
+
+	if handler.Limiter.InFlight() != 0 {
+		t.Errorf("%d slots still taken", handler.Limiter.InFlight())
+	}
+}
+
+func TestBatchLimits(t *testing.T) {
+	handler, server := newFakeHandler(t)
+	handler.Cache = NewMemoryCache()
+	handler.Ctx = context.Background()
+	handler.MaxBatchSize = 2
+	handler.BatchTimeout = 50 * time.Millisecond
+
+	app := fiber.New()
+	app.Get("/profiles", handler.GetProfiles)
+
+	code, _ := doRequest(t, app, jsonRequest("/profiles", `{"uuids":["`+notch.Id+`","`+jeb.Id+`","`+alex.Id+`"]}`))
+	if code != fiber.StatusRequestEntityTooLarge {
+		t.Errorf("GET /profiles over MaxBatchSize = %d, want 413", code)
+	}
+
+	//fetches outlasting the BatchTimeout are abandoned
+	server.SetLatency(500 * time.Millisecond)
+	start := time.Now()
+	code, body := doRequest(t, app, jsonRequest("/profiles", `{"uuids":["`+notch.Id+`","`+jeb.Id+`"]}`))
+
+	if elapsed := time.Since(start); elapsed > 400*time.Millisecond {
+		t.Errorf("GET /profiles took %v, want it cut off by the BatchTimeout", elapsed)
+	}
+
+	response := &struct {
+		Errors map[string]*BatchError `json:"errors"`
+	}{}
+
+	if err := json.Unmarshal(body, response); err != nil || code != fiber.StatusOK {
+		t.Fatalf("GET /profiles = %d %s", code, body)
+	}
+
+	for _, id := range []string{notch.Id, jeb.Id} {
+		if err := response.Errors[id]; err == nil || err.Code != fiber.StatusGatewayTimeout {
+			t.Errorf("GET /profiles error for %s = %+v, want 504 in %s", id, err, strings.TrimSpace(string(body)))
+		}
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	handler, server := newFakeHandler(t)
+	handler.Cache = NewMemoryCache()
+	handler.RequestTimeout = 50 * time.Millisecond
+
+	app := fiber.New()
+	app.Use(handler.RequestContext)
+	app.Get("/profile/:uuid", handler.GetProfile)
+
+	//the upstream work of a request is abandoned once the RequestTimeout passes
+	server.SetLatency(500 * time.Millisecond)
+	start := time.Now()
+	code, body := doRequest(t, app, httptest.NewRequest(fiber.MethodGet, "/profile/"+notch.Id, nil))
+
+	if elapsed := time.Since(start); code != fiber.StatusGatewayTimeout || elapsed > 400*time.Millisecond {
+		t.Errorf("GET /profile past the RequestTimeout = %d in %v, want 504 %s", code, elapsed, body)
+	}
+
+	//nothing was cached for the abandoned request, the next one fetches again
+	server.SetLatency(0)
+
+	code, body = doRequest(t, app, httptest.NewRequest(fiber.MethodGet, "/profile/"+notch.Id, nil))
+	if code != fiber.StatusOK {
+		t.Errorf("GET /profile after the timeout = %d %s", code, body)
+	}
+}
@@ -0,0 +1,211 @@
+package api
+
+import (
+	"container/list"
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v9"
+	"github.com/google/uuid"
+)
+
+// DefaultLocalCacheSize how many bytes of keys and values the in-process cache holds by default
+const DefaultLocalCacheSize = 64 << 20
+
+// DefaultLocalTTL how long entries without a per-type TTL are kept in the in-process cache
+const DefaultLocalTTL = 30 * time.Second
+
+// InvalidationChannel redis channel replicas announce the keys they wrote on, so the others drop their local copies
+const InvalidationChannel = "cache:invalidate"
+
+// DefaultLocalTTLs how long entries are kept in the in-process cache by key prefix. Textures never change under
+// their id, profiles and usernames are kept briefly so replicas only serve each others writes late for a moment
+// when an invalidation is lost
+var DefaultLocalTTLs = map[string]time.Duration{
+	"texture:":  RenderTTL,
+	"render:":   RenderTTL,
+	"profile:":  DefaultLocalTTL,
+	"username:": DefaultLocalTTL,
+}
+
+// LocalCache a size bounded in-process LRU tier in front of redis
+type LocalCache struct {
+	// MaxBytes how many bytes of keys and values are kept before the least recently used entries are evicted
+	MaxBytes int
+	// TTLs how long entries are kept by key prefix, DefaultLocalTTL for keys without a matching prefix
+	TTLs map[string]time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+	size    int
+	token   string
+}
+
+// localEntry a value in the LocalCache and when it expires
+type localEntry struct {
+	key       string
+	value     string
+	expiresAt time.Time
+}
+
+func (e *localEntry) size() int {
+	return len(e.key) + len(e.value)
+}
+
+// NewLocalCache creates a LocalCache holding at most maxBytes, DefaultLocalCacheSize when 0
+func NewLocalCache(maxBytes int) *LocalCache {
+	if maxBytes <= 0 {
+		maxBytes = DefaultLocalCacheSize
+	}
+
+	return &LocalCache{
+		MaxBytes: maxBytes,
+		TTLs:     DefaultLocalTTLs,
+		entries:  make(map[string]*list.Element),
+		lru:      list.New(),
+		token:    uuid.NewString(),
+	}
+}
+
+// ttl how long key may be kept locally, never longer than it is kept in redis
+func (l *LocalCache) ttl(key string, remaining time.Duration) time.Duration {
+	ttl := DefaultLocalTTL
+
+	for prefix, prefixTTL := range l.TTLs {
+		if strings.HasPrefix(key, prefix) {
+			ttl = prefixTTL
+			break
+		}
+	}
+
+	if remaining > 0 && remaining < ttl {
+		return remaining
+	}
+
+	return ttl
+}
+
+// Get returns the value of key if it is cached and not expired
+func (l *LocalCache) Get(key string) (string, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	el, ok := l.entries[key]
+	if !ok {
+		return "", false
+	}
+
+	entry := el.Value.(*localEntry)
+
+	if time.Now().After(entry.expiresAt) {
+		l.remove(el)
+		return "", false
+	}
+
+	l.lru.MoveToFront(el)
+	return entry.value, true
+}
+
+// Put stores value under key for its per-type TTL, capped at remaining when it expires sooner in redis
+func (l *LocalCache) Put(key string, value string, remaining time.Duration) {
+	entry := &localEntry{
+		key:       key,
+		value:     value,
+		expiresAt: time.Now().Add(l.ttl(key, remaining)),
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if el, ok := l.entries[key]; ok {
+		l.remove(el)
+	}
+
+	//values larger than the whole budget would only evict everything else
+	if entry.size() > l.MaxBytes {
+		return
+	}
+
+	l.entries[key] = l.lru.PushFront(entry)
+	l.size += entry.size()
+
+	for l.size > l.MaxBytes {
+		l.remove(l.lru.Back())
+	}
+}
+
+// Delete drops key from the cache
+func (l *LocalCache) Delete(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if el, ok := l.entries[key]; ok {
+		l.remove(el)
+	}
+}
+
+// Len returns how many entries are cached
+func (l *LocalCache) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.lru.Len()
+}
+
+// Size returns how many bytes of keys and values are cached
+func (l *LocalCache) Size() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.size
+}
+
+func (l *LocalCache) remove(el *list.Element) {
+	entry := l.lru.Remove(el).(*localEntry)
+	delete(l.entries, entry.key)
+	l.size -= entry.size()
+}
+
+// publish tells the other replicas key was written, on a client or in a pipeline
+func (l *LocalCache) publish(ctx context.Context, rdb redis.Cmdable, key string) error {
+	return rdb.Publish(ctx, InvalidationChannel, l.token+" "+key).Err()
+}
+
+// Subscribe drops the keys other replicas announce on the InvalidationChannel until ctx is done
+func (l *LocalCache) Subscribe(ctx context.Context, rdb *redis.Client) error {
+	pubsub := rdb.Subscribe(ctx, InvalidationChannel)
+
+	//wait for the subscription, so no write announced after Subscribe returns is missed
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return err
+	}
+
+	go func(ctx context.Context, pubsub *redis.PubSub) {
+		defer pubsub.Close()
+		ch := pubsub.Channel()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-ch:
+				if !ok {
+					return
+				}
+
+				token, key, found := strings.Cut(msg.Payload, " ")
+
+				//our own writes are already in the local cache
+				if found && token != l.token {
+					l.Delete(key)
+				}
+			}
+		}
+	}(ctx, pubsub)
+
+	return nil
+}
@@ -0,0 +1,281 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"bed.gg/minecraft-api/v2/src/logger"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v9"
+)
+
+func TestLocalCacheEviction(t *testing.T) {
+	l := NewLocalCache(64)
+
+	l.Put("texture:a", "0123456789abcdef0123", 0)
+	l.Put("texture:b", "0123456789abcdef0123", 0)
+
+	//touching a makes b the least recently used entry
+	if _, ok := l.Get("texture:a"); !ok {
+		t.Fatal("texture:a missing")
+	}
+
+	l.Put("texture:c", "0123456789abcdef0123", 0)
+
+	if _, ok := l.Get("texture:b"); ok {
+		t.Error("texture:b was not evicted")
+	}
+
+	if _, ok := l.Get("texture:a"); !ok {
+		t.Error("texture:a was evicted")
+	}
+
+	if l.Size() > l.MaxBytes {
+		t.Errorf("Size = %d, want at most %d", l.Size(), l.MaxBytes)
+	}
+
+	//entries expire with the redis key they copy
+	l.Put("profile:a", "{}", time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	if _, ok := l.Get("profile:a"); ok {
+		t.Error("profile:a outlived its ttl")
+	}
+}
+
+// newReplicas creates handlers sharing one redis, each with a local tier kept consistent through pub/sub
+func newReplicas(t *testing.T, mr *miniredis.Miniredis, n int) []*Handler {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	var replicas []*Handler
+	for i := 0; i < n; i++ {
+		cache := NewRedisCache(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+		cache.Local = NewLocalCache(0)
+
+		if err := cache.Local.Subscribe(ctx, cache.Rdb); err != nil {
+			t.Fatal(err)
+		}
+
+		h := &Handler{
+			Logger: logger.NewLogger(),
+			Ctx:    ctx,
+			Cache:  cache,
+		}
+
+		replicas = append(replicas, h)
+	}
+
+	return replicas
+}
+
+// awaitItem waits until the replica reads want under key
+func awaitItem(t *testing.T, h *Handler, key string, want string) {
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, item, _ := h.CacheGet(context.Background(), key); item == want {
+			return
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("local copy of %s was not invalidated", key)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestLocalCacheInvalidation(t *testing.T) {
+	mr := miniredis.RunT(t)
+
+	//two replicas sharing one redis
+	replicas := newReplicas(t, mr, 2)
+
+	if err := replicas[0].CachePut(context.Background(), "texture:a", "old", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, item, _ := replicas[1].CacheGet(context.Background(), "texture:a"); item != "old" {
+		t.Fatalf("CacheGet = %s, want old", item)
+	}
+
+	//once read, textures are served without asking redis
+	mr.FlushAll()
+
+	if exists, item, _ := replicas[1].CacheGet(context.Background(), "texture:a"); !exists || item != "old" {
+		t.Fatalf("CacheGet after flush = %v %s, want the local copy", exists, item)
+	}
+
+	//a write on one replica drops the copy of the other
+	if err := replicas[0].CachePut(context.Background(), "texture:a", "new", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	awaitItem(t, replicas[1], "texture:a", "new")
+}
+
+func TestLocalCacheUnchangedWrite(t *testing.T) {
+	mr := miniredis.RunT(t)
+	replicas := newReplicas(t, mr, 2)
+
+	for _, key := range []string{"texture:a", "texture:b"} {
+		if err := replicas[0].CachePut(context.Background(), key, "same", time.Hour); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, item, _ := replicas[1].CacheGet(context.Background(), key); item != "same" {
+			t.Fatalf("CacheGet %s = %s, want same", key, item)
+		}
+	}
+
+	//writing the values again, alone and in a batch, leaves the copies of the other replica alone
+	if err := replicas[0].CachePut(context.Background(), "texture:a", "same", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := replicas[0].CacheMultiPut(context.Background(), map[string]string{"texture:b": "same"}, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	//invalidations arrive in order, so once a later change is seen the unchanged writes would have been too
+	mr.Set("texture:a", "only in redis")
+	mr.Set("texture:b", "only in redis")
+
+	replicas[1].Cache.(*RedisCache).Local.Put("texture:c", "old", time.Hour)
+
+	if err := replicas[0].CachePut(context.Background(), "texture:c", "new", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	awaitItem(t, replicas[1], "texture:c", "new")
+
+	for _, key := range []string{"texture:a", "texture:b"} {
+		if _, item, _ := replicas[1].CacheGet(context.Background(), key); item != "same" {
+			t.Errorf("CacheGet %s after an unchanged write = %s, want the local copy", key, item)
+		}
+	}
+}
+
+// failPublish a redis hook failing every PUBLISH, alone or in a pipeline
+type failPublish struct{}
+
+func (failPublish) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+func (failPublish) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if cmd.Name() == "publish" {
+			return errors.New("publish failed")
+		}
+
+		return next(ctx, cmd)
+	}
+}
+
+func (failPublish) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		for _, cmd := range cmds {
+			if cmd.Name() == "publish" {
+				return errors.New("publish failed")
+			}
+		}
+
+		return next(ctx, cmds)
+	}
+}
+
+func TestLocalCacheInvalidationFailure(t *testing.T) {
+	mr := miniredis.RunT(t)
+	h := newReplicas(t, mr, 1)[0]
+	cache := h.Cache.(*RedisCache)
+	cache.Rdb.AddHook(failPublish{})
+
+	//the writes succeed, only telling the other replicas about them fails
+	if err := cache.Put(context.Background(), "texture:a", "a", time.Hour); !errors.Is(err, ErrInvalidation) {
+		t.Errorf("Put = %v, want ErrInvalidation", err)
+	}
+
+	if err := cache.MultiPut(context.Background(), map[string]string{"texture:b": "b"}, time.Hour); !errors.Is(err, ErrInvalidation) {
+		t.Errorf("MultiPut = %v, want ErrInvalidation", err)
+	}
+
+	for key, want := range map[string]string{"texture:a": "a", "texture:b": "b"} {
+		if item, _ := mr.Get(key); item != want {
+			t.Errorf("redis %s = %s, want %s", key, item, want)
+		}
+	}
+
+	if err := cache.Delete(context.Background(), "texture:a"); !errors.Is(err, ErrInvalidation) {
+		t.Errorf("Delete = %v, want ErrInvalidation", err)
+	}
+
+	if mr.Exists("texture:a") {
+		t.Error("texture:a was not deleted")
+	}
+
+	//the handler does not report them as failed writes
+	if err := h.CachePut(context.Background(), "texture:c", "c", time.Hour); err != nil {
+		t.Errorf("CachePut = %v, want nil", err)
+	}
+
+	if err := h.CacheMultiPut(context.Background(), map[string]string{"texture:d": "d"}, time.Hour); err != nil {
+		t.Errorf("CacheMultiPut = %v, want nil", err)
+	}
+
+	//a failed write still is
+	mr.SetError("server down")
+
+	if err := h.CachePut(context.Background(), "texture:e", "e", time.Hour); err == nil || errors.Is(err, ErrInvalidation) {
+		t.Errorf("CachePut with redis down = %v, want a write error", err)
+	}
+}
+
+func TestLocalCacheUnchangedEntry(t *testing.T) {
+	mr := miniredis.RunT(t)
+	replicas := newReplicas(t, mr, 2)
+	ctx := context.Background()
+
+	for _, key := range []string{"texture:a", "profile:a"} {
+		if err := replicas[0].cachePutEntry(ctx, key, "same", time.Hour); err != nil {
+			t.Fatal(err)
+		}
+
+		if entry, _ := replicas[1].cacheGetEntry(ctx, key); entry == nil || entry.Value != "same" {
+			t.Fatalf("cacheGetEntry %s = %+v, want same", key, entry)
+		}
+	}
+
+	//storing the same texture again is a new entry with a later time, but the same texture
+	first, _ := mr.Get("texture:a")
+	time.Sleep(2 * time.Millisecond)
+
+	for _, key := range []string{"texture:a", "profile:a"} {
+		if err := replicas[0].cachePutEntry(ctx, key, "same", time.Hour); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mr.Set("texture:a", "only in redis")
+
+	//the profile copy is dropped, its age changed, while the texture copy stays
+	profile, _ := mr.Get("profile:a")
+	awaitItem(t, replicas[1], "profile:a", profile)
+
+	if _, item, _ := replicas[1].CacheGet(ctx, "texture:a"); item != first {
+		t.Errorf("CacheGet texture:a after an unchanged write = %s, want the local copy %s", item, first)
+	}
+
+	//a different texture under the key still reaches the other replica
+	if err := replicas[0].cachePutEntry(ctx, "texture:a", "other", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	latest, _ := mr.Get("texture:a")
+	awaitItem(t, replicas[1], "texture:a", latest)
+}
@@ -0,0 +1,473 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"strconv"
+	"strings"
+	"time"
+
+	"bed.gg/minecraft-api/v2/src/render"
+	"bed.gg/minecraft-api/v2/src/serverping"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	DefaultRenderSize = 128
+	MinRenderSize     = 8
+	MaxRenderSize     = 512
+)
+
+// ServerPingTimeout bounds resolving, dialing and talking to a pinged server, within the deadline of the request
+const ServerPingTimeout = 5 * time.Second
+
+// lookupProfile returns the profile and its age from the cache, fetching and caching it from the mojang api on a miss
+func (h *Handler) lookupProfile(ctx context.Context, playerUUID UUID) (int, *ProfileResponse, []byte, time.Duration, []error) {
+	code, item, age, errs := h.swr(ctx, profileKey(playerUUID), TTL, func(ctx context.Context) (int, string, []error) {
+		code, profileResponse, body, errs := h.FetchProfile(ctx, playerUUID)
+
+		if len(errs) > 0 || profileResponse == nil {
+			return code, "", errs
+		}
+
+		return code, string(body), []error{}
+	})
+
+	if len(errs) > 0 || code != fiber.StatusOK {
+		return code, nil, nil, 0, errs
+	}
+
+	profileResponse := &ProfileResponse{}
+	err := json.Unmarshal([]byte(item), profileResponse)
+
+	if err != nil {
+		return fiber.StatusInternalServerError, nil, nil, 0, []error{err}
+	}
+
+	return fiber.StatusOK, profileResponse, []byte(item), age, []error{}
+}
+
+// lookupTexture returns the raw texture and its age from the cache, fetching and caching it from the mojang api on a miss
+func (h *Handler) lookupTexture(ctx context.Context, textureid string) (int, []byte, time.Duration, []error) {
+	code, item, age, errs := h.swr(ctx, textureKey(textureid), TextureTTL, func(ctx context.Context) (int, string, []error) {
+		code, textureBase64, _, errs := h.FetchTexture(ctx, textureid)
+
+		if len(errs) > 0 || textureBase64 == "" {
+			return code, "", errs
+		}
+
+		return code, textureBase64, []error{}
+	})
+
+	if len(errs) > 0 || code != fiber.StatusOK {
+		return code, nil, 0, errs
+	}
+
+	body, err := base64.StdEncoding.DecodeString(item)
+
+	if err != nil {
+		return fiber.StatusInternalServerError, nil, 0, []error{err}
+	}
+
+	return fiber.StatusOK, body, age, []error{}
+}
+
+// profileLookup a profile resolved by lookupProfiles
+type profileLookup struct {
+	code    int
+	profile *ProfileResponse
+	age     time.Duration
+	errs    []error
+}
+
+// lookupProfiles resolves the profiles like lookupProfile, reading and writing the cache in one round trip each.
+// Lookups still running when ctx is done fail with 504. The lookups are in the order of uuids
+func (h *Handler) lookupProfiles(ctx context.Context, uuids []UUID) []profileLookup {
+	keys := make([]string, len(uuids))
+	for i, playerUUID := range uuids {
+		keys[i] = profileKey(playerUUID)
+	}
+
+	results := h.swrMulti(ctx, keys, TTL, func(ctx context.Context, i int) (int, string, []error) {
+		code, profileResponse, body, errs := h.FetchProfile(ctx, uuids[i])
+
+		if len(errs) > 0 || profileResponse == nil {
+			return code, "", errs
+		}
+
+		return code, string(body), []error{}
+	})
+
+	lookups := make([]profileLookup, len(results))
+
+	for i, result := range results {
+		if len(result.errs) > 0 || result.code != fiber.StatusOK {
+			lookups[i] = profileLookup{result.code, nil, 0, result.errs}
+			continue
+		}
+
+		profileResponse := &ProfileResponse{}
+		err := json.Unmarshal([]byte(result.value), profileResponse)
+
+		if err != nil {
+			lookups[i] = profileLookup{fiber.StatusInternalServerError, nil, 0, []error{err}}
+			continue
+		}
+
+		lookups[i] = profileLookup{fiber.StatusOK, profileResponse, result.age, []error{}}
+	}
+
+	return lookups
+}
+
+// textureLookup a texture resolved by lookupTextureIds
+type textureLookup struct {
+	code int
+	body []byte
+	age  time.Duration
+	errs []error
+}
+
+// lookupTextureIds resolves the textures like lookupTexture, reading and writing the cache in one round trip each.
+// Lookups still running when ctx is done fail with 504. The lookups are in the order of textureids
+func (h *Handler) lookupTextureIds(ctx context.Context, textureids []string) []textureLookup {
+	keys := make([]string, len(textureids))
+	for i, textureid := range textureids {
+		keys[i] = textureKey(textureid)
+	}
+
+	results := h.swrMulti(ctx, keys, TextureTTL, func(ctx context.Context, i int) (int, string, []error) {
+		code, textureBase64, _, errs := h.FetchTexture(ctx, textureids[i])
+
+		if len(errs) > 0 || textureBase64 == "" {
+			return code, "", errs
+		}
+
+		return code, textureBase64, []error{}
+	})
+
+	lookups := make([]textureLookup, len(results))
+
+	for i, result := range results {
+		if len(result.errs) > 0 || result.code != fiber.StatusOK {
+			lookups[i] = textureLookup{result.code, nil, 0, result.errs}
+			continue
+		}
+
+		body, err := base64.StdEncoding.DecodeString(result.value)
+
+		if err != nil {
+			lookups[i] = textureLookup{fiber.StatusInternalServerError, nil, 0, []error{err}}
+			continue
+		}
+
+		lookups[i] = textureLookup{fiber.StatusOK, body, result.age, []error{}}
+	}
+
+	return lookups
+}
+
+// usernameLookup a username resolved by lookupUsernames
+type usernameLookup struct {
+	code     int
+	username *UsernameResponse
+	errs     []error
+}
+
+// lookupUsernames resolves the usernames from the cache, fetching the misses from the mojang batch endpoint and caching them.
+// Every name of a batch that failed carries the failure of its batch, names mojang left out are 404s. The lookups are in the order of usernames
+func (h *Handler) lookupUsernames(ctx context.Context, usernames []string) []usernameLookup {
+	lookups := make([]usernameLookup, len(usernames))
+
+	keys := make([]string, len(usernames))
+	for i, username := range usernames {
+		keys[i] = usernameKey(username)
+	}
+
+	items, err := h.CacheMultiGet(ctx, keys)
+	if err != nil {
+		for i := range lookups {
+			lookups[i] = usernameLookup{fiber.StatusInternalServerError, nil, []error{err}}
+		}
+
+		return lookups
+	}
+
+	//index the misses by their lowercase name, the form mojang's answers are matched against
+	var misses []string
+	missIdx := make(map[string]int)
+
+	for i, username := range usernames {
+		item, exists := items[keys[i]]
+
+		if !exists {
+			misses = append(misses, username)
+			missIdx[strings.ToLower(username)] = i
+			continue
+		}
+
+		if item == missingUsername {
+			lookups[i] = usernameLookup{fiber.StatusNotFound, nil, []error{}}
+			continue
+		}
+
+		usernameResponse := &UsernameResponse{}
+		if err := json.Unmarshal([]byte(item), usernameResponse); err != nil {
+			lookups[i] = usernameLookup{fiber.StatusInternalServerError, nil, []error{err}}
+			continue
+		}
+
+		lookups[i] = usernameLookup{fiber.StatusOK, usernameResponse, []error{}}
+	}
+
+	if len(misses) == 0 {
+		return lookups
+	}
+
+	found := make(map[string]string)
+	missing := make(map[string]string)
+
+	for _, response := range h.FetchUUIDs(ctx, misses) {
+		//a failed batch fails only the names it was resolving
+		if len(response.Errs) > 0 || response.Code != fiber.StatusOK {
+			for _, username := range response.Names {
+				lookups[missIdx[strings.ToLower(username)]] = usernameLookup{response.Code, nil, response.Errs}
+			}
+
+			continue
+		}
+
+		for _, usernameResponse := range response.Usernames {
+			i, ok := missIdx[strings.ToLower(usernameResponse.Name)]
+			if !ok {
+				continue
+			}
+
+			lookups[i] = usernameLookup{fiber.StatusOK, usernameResponse, []error{}}
+
+			out, err := json.Marshal(usernameResponse)
+			if err != nil {
+				h.Logger.Error("%v", err)
+				continue
+			}
+
+			found[keys[i]] = string(out)
+		}
+
+		//usernames left out of the batch response are unknown to mojang
+		for _, username := range response.Names {
+			i := missIdx[strings.ToLower(username)]
+
+			if lookups[i].username == nil {
+				lookups[i] = usernameLookup{fiber.StatusNotFound, nil, []error{}}
+				missing[keys[i]] = missingUsername
+			}
+		}
+	}
+
+	if err := h.CacheMultiPut(ctx, found, UsernameTTL); err != nil {
+		h.Logger.Error("Failed to cache %d usernames: %v", len(found), err)
+	}
+
+	if err := h.CacheMultiPut(ctx, missing, h.negativeTTL()); err != nil {
+		h.Logger.Error("Failed to cache %d missing usernames: %v", len(missing), err)
+	}
+
+	return lookups
+}
+
+// lookupTextures resolves the decoded textures property of the player
+func (h *Handler) lookupTextures(ctx context.Context, playerUUID UUID) (int, *TextureResponse, []error) {
+	code, profileResponse, _, _, errs := h.lookupProfile(ctx, playerUUID)
+
+	if profileResponse == nil {
+		return code, nil, errs
+	}
+
+	textureResponse, err := profileResponse.DecodeTextures()
+
+	if err != nil {
+		return fiber.StatusInternalServerError, nil, []error{err}
+	}
+
+	return fiber.StatusOK, textureResponse, []error{}
+}
+
+// lookupSkin resolves the skin texture id and model of the player
+func (h *Handler) lookupSkin(ctx context.Context, playerUUID UUID) (int, string, bool, []error) {
+	code, textureResponse, errs := h.lookupTextures(ctx, playerUUID)
+
+	if textureResponse == nil {
+		return code, "", false, errs
+	}
+
+	textureid, slim := textureResponse.SkinTexture()
+	return fiber.StatusOK, textureid, slim, []error{}
+}
+
+// lookupSkinImage fetches the skin texture and decodes it into a render.Skin
+func (h *Handler) lookupSkinImage(ctx context.Context, textureid string, slim bool) (*render.Skin, int, []error) {
+	code, body, _, errs := h.lookupTexture(ctx, textureid)
+
+	if body == nil {
+		return nil, code, errs
+	}
+
+	img, err := png.Decode(bytes.NewReader(body))
+
+	if err != nil {
+		return nil, fiber.StatusBadGateway, []error{err}
+	}
+
+	skin, err := render.NewSkin(img, slim)
+
+	if err != nil {
+		return nil, fiber.StatusBadGateway, []error{err}
+	}
+
+	return skin, fiber.StatusOK, []error{}
+}
+
+// lookupCapeImage fetches the cape texture and normalizes it for rendering
+func (h *Handler) lookupCapeImage(ctx context.Context, textureid string) (*image.NRGBA, int, []error) {
+	code, body, _, errs := h.lookupTexture(ctx, textureid)
+
+	if body == nil {
+		return nil, code, errs
+	}
+
+	img, err := png.Decode(bytes.NewReader(body))
+
+	if err != nil {
+		return nil, fiber.StatusBadGateway, []error{err}
+	}
+
+	cape, err := render.NewCape(img)
+
+	if err != nil {
+		return nil, fiber.StatusBadGateway, []error{err}
+	}
+
+	return cape, fiber.StatusOK, []error{}
+}
+
+// pinger creates the Pinger of a server lookup, dialing from the next ip of the IPPool
+func (h *Handler) pinger() *serverping.Pinger {
+	return &serverping.Pinger{
+		Timeout:      ServerPingTimeout,
+		LocalIP:      h.pingIP(),
+		AllowPrivate: h.AllowPrivateServers,
+	}
+}
+
+// lookupServer returns the java server status from the cache, pinging the server and caching its status on a miss
+func (h *Handler) lookupServer(ctx context.Context, address string, remoteAddr string) (*serverping.Response, int, error) {
+	key := fmt.Sprintf("server:java:%s", address)
+	exists, item, err := h.CacheGet(ctx, key)
+
+	if err != nil {
+		return nil, fiber.StatusInternalServerError, err
+	}
+
+	response := &serverping.Response{}
+
+	if exists {
+		h.Logger.Info("[%s] Cache Hit for [%s]", key, remoteAddr)
+		err := json.Unmarshal([]byte(item), response)
+
+		if err != nil {
+			return nil, fiber.StatusInternalServerError, err
+		}
+
+		return response, fiber.StatusOK, nil
+	}
+
+	h.Logger.Info("[%s] Cache Miss for %s", key, remoteAddr)
+	pinger := h.pinger()
+
+	response, err = pinger.Ping(ctx, address)
+
+	if err != nil {
+		return nil, fiber.StatusBadGateway, err
+	}
+
+	out, err := json.Marshal(response)
+
+	if err != nil {
+		return nil, fiber.StatusInternalServerError, err
+	}
+
+	err = h.CachePut(ctx, key, string(out), ServerTTL)
+	if err != nil {
+		h.Logger.Error("[%s] Failed to cache server: %v", key, err)
+	}
+
+	return response, fiber.StatusOK, nil
+}
+
+// lookupBedrockServer returns the bedrock server status from the cache, pinging the server and caching its status on a miss
+func (h *Handler) lookupBedrockServer(ctx context.Context, address string, remoteAddr string) (*serverping.BedrockResponse, int, error) {
+	key := fmt.Sprintf("server:bedrock:%s", address)
+	exists, item, err := h.CacheGet(ctx, key)
+
+	if err != nil {
+		return nil, fiber.StatusInternalServerError, err
+	}
+
+	response := &serverping.BedrockResponse{}
+
+	if exists {
+		h.Logger.Info("[%s] Cache Hit for [%s]", key, remoteAddr)
+		err := json.Unmarshal([]byte(item), response)
+
+		if err != nil {
+			return nil, fiber.StatusInternalServerError, err
+		}
+
+		return response, fiber.StatusOK, nil
+	}
+
+	h.Logger.Info("[%s] Cache Miss for %s", key, remoteAddr)
+	pinger := h.pinger()
+
+	response, err = pinger.PingBedrock(ctx, address)
+
+	if err != nil {
+		return nil, fiber.StatusBadGateway, err
+	}
+
+	out, err := json.Marshal(response)
+
+	if err != nil {
+		return nil, fiber.StatusInternalServerError, err
+	}
+
+	err = h.CachePut(ctx, key, string(out), ServerTTL)
+	if err != nil {
+		h.Logger.Error("[%s] Failed to cache server: %v", key, err)
+	}
+
+	return response, fiber.StatusOK, nil
+}
+
+// parseRenderQuery parses the size and overlay query parameters shared by the render routes
+func parseRenderQuery(c *fiber.Ctx) (int, bool, error) {
+	size, err := strconv.Atoi(c.Query("size", strconv.Itoa(DefaultRenderSize)))
+
+	if err != nil || size < MinRenderSize || size > MaxRenderSize {
+		return 0, false, fmt.Errorf("bad size: %s, must be between %d and %d", c.Query("size"), MinRenderSize, MaxRenderSize)
+	}
+
+	overlay, err := strconv.ParseBool(c.Query("overlay", "true"))
+
+	if err != nil {
+		return 0, false, fmt.Errorf("bad overlay: %s", c.Query("overlay"))
+	}
+
+	return size, overlay, nil
+}
@@ -0,0 +1,139 @@
+package api
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryCache a Cache held in process, for running a single instance without redis in dev and tests.
+// Expired entries are dropped when they are next read or by Sweep
+type MemoryCache struct {
+	mu      sync.RWMutex
+	entries map[string]*memoryEntry
+}
+
+// memoryEntry a value in the MemoryCache and when it expires, never when expiresAt is zero
+type memoryEntry struct {
+	value     string
+	expiresAt time.Time
+}
+
+func (e *memoryEntry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && now.After(e.expiresAt)
+}
+
+// NewMemoryCache creates an empty MemoryCache
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{
+		entries: make(map[string]*memoryEntry),
+	}
+}
+
+// lookup returns the entry of key, nil when it does not exist or expired
+func (m *MemoryCache) lookup(key string) *memoryEntry {
+	m.mu.RLock()
+	entry, ok := m.entries[key]
+	m.mu.RUnlock()
+
+	if !ok {
+		return nil
+	}
+
+	if entry.expired(time.Now()) {
+		m.mu.Lock()
+		if m.entries[key] == entry {
+			delete(m.entries, key)
+		}
+		m.mu.Unlock()
+
+		return nil
+	}
+
+	return entry
+}
+
+func (m *MemoryCache) Get(ctx context.Context, key string) (bool, string, error) {
+	entry := m.lookup(key)
+
+	if entry == nil {
+		return false, "", nil
+	}
+
+	return true, entry.value, nil
+}
+
+func (m *MemoryCache) Put(ctx context.Context, key string, value string, ttl time.Duration) error {
+	entry := &memoryEntry{value: value}
+
+	if ttl > 0 {
+		entry.expiresAt = time.Now().Add(ttl)
+	}
+
+	m.mu.Lock()
+	m.entries[key] = entry
+	m.mu.Unlock()
+
+	return nil
+}
+
+func (m *MemoryCache) MultiPut(ctx context.Context, items map[string]string, ttl time.Duration) error {
+	for key, value := range items {
+		if err := m.Put(ctx, key, value, ttl); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *MemoryCache) MultiGet(ctx context.Context, keys []string) (map[string]string, error) {
+	items := make(map[string]string, len(keys))
+
+	for _, key := range keys {
+		if entry := m.lookup(key); entry != nil {
+			items[key] = entry.value
+		}
+	}
+
+	return items, nil
+}
+
+func (m *MemoryCache) Delete(ctx context.Context, keys ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, key := range keys {
+		delete(m.entries, key)
+	}
+
+	return nil
+}
+
+func (m *MemoryCache) TTL(ctx context.Context, key string) (bool, time.Duration, error) {
+	entry := m.lookup(key)
+
+	if entry == nil {
+		return false, 0, nil
+	}
+
+	if entry.expiresAt.IsZero() {
+		return true, 0, nil
+	}
+
+	return true, time.Until(entry.expiresAt), nil
+}
+
+// Sweep drops every expired entry
+func (m *MemoryCache) Sweep() {
+	now := time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for key, entry := range m.entries {
+		if entry.expired(now) {
+			delete(m.entries, key)
+		}
+	}
+}
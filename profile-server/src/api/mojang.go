@@ -0,0 +1,288 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+)
+
+// DefaultMojangHosts the public mojang api hosts
+var DefaultMojangHosts = MojangHosts{
+	SessionServer: "https://sessionserver.mojang.com",
+	API:           "https://api.mojang.com",
+	Textures:      "https://textures.minecraft.net",
+}
+
+// MojangHosts base urls of the upstream apis, pointing them at a mirror or a local stand-in
+type MojangHosts struct {
+	SessionServer string
+	API           string
+	Textures      string
+}
+
+// DefaultDialTimeout how long connecting to the upstream may take by default
+const DefaultDialTimeout = 3 * time.Second
+
+// DefaultReadTimeout how long a single upstream request may take by default, from sending it to reading the response
+const DefaultReadTimeout = 5 * time.Second
+
+// MojangClient the upstream calls of the api, returning the status code and the raw body of the response.
+// Calls give up once ctx is done, reporting 504 when its deadline passed
+type MojangClient interface {
+	// Profile fetches the signed profile of the player from the session server
+	Profile(ctx context.Context, playerUUID UUID) (int, []byte, []error)
+	// UUID resolves a single username
+	UUID(ctx context.Context, username string) (int, []byte, []error)
+	// UUIDs resolves up to MojangBatchSize usernames with a single call
+	UUIDs(ctx context.Context, usernames []string) (int, []byte, []error)
+	// Texture fetches the png of a texture
+	Texture(ctx context.Context, textureid string) (int, []byte, []error)
+}
+
+// FasthttpMojangClient the default MojangClient, dialing from the IPPool of its Handler
+type FasthttpMojangClient struct {
+	Hosts MojangHosts
+	// Retry policy applied to every request, each retry dials from the next ip in the IPPool
+	Retry RetryPolicy
+	// DialTimeout bounds connecting to the upstream, DefaultDialTimeout when 0
+	DialTimeout time.Duration
+	// ReadTimeout bounds every single attempt, DefaultReadTimeout when 0
+	ReadTimeout time.Duration
+	handler     *Handler
+}
+
+// NewMojangClient creates a FasthttpMojangClient for the handler, empty hosts fall back to DefaultMojangHosts
+func NewMojangClient(h *Handler, hosts MojangHosts) *FasthttpMojangClient {
+	if hosts.SessionServer == "" {
+		hosts.SessionServer = DefaultMojangHosts.SessionServer
+	}
+
+	if hosts.API == "" {
+		hosts.API = DefaultMojangHosts.API
+	}
+
+	if hosts.Textures == "" {
+		hosts.Textures = DefaultMojangHosts.Textures
+	}
+
+	hosts.SessionServer = strings.TrimSuffix(hosts.SessionServer, "/")
+	hosts.API = strings.TrimSuffix(hosts.API, "/")
+	hosts.Textures = strings.TrimSuffix(hosts.Textures, "/")
+
+	return &FasthttpMojangClient{
+		Hosts:   hosts,
+		Retry:   DefaultRetryPolicy,
+		handler: h,
+	}
+}
+
+// mojang returns the configured MojangClient, defaulting to the public mojang api
+func (h *Handler) mojang() MojangClient {
+	if h.Mojang != nil {
+		return h.Mojang
+	}
+
+	return NewMojangClient(h, DefaultMojangHosts)
+}
+
+func (m *FasthttpMojangClient) Profile(ctx context.Context, playerUUID UUID) (int, []byte, []error) {
+	return m.request(ctx, fiber.MethodGet, nil, fmt.Sprintf("%s/session/minecraft/profile/%s?unsigned=false", m.Hosts.SessionServer, playerUUID))
+}
+
+func (m *FasthttpMojangClient) UUID(ctx context.Context, username string) (int, []byte, []error) {
+	return m.request(ctx, fiber.MethodGet, nil, fmt.Sprintf("%s/users/profiles/minecraft/%s", m.Hosts.API, username))
+}
+
+func (m *FasthttpMojangClient) UUIDs(ctx context.Context, usernames []string) (int, []byte, []error) {
+	payload, err := json.Marshal(usernames)
+
+	if err != nil {
+		return fiber.StatusInternalServerError, nil, []error{err}
+	}
+
+	return m.request(ctx, fiber.MethodPost, payload, fmt.Sprintf("%s/profiles/minecraft", m.Hosts.API))
+}
+
+func (m *FasthttpMojangClient) Texture(ctx context.Context, textureid string) (int, []byte, []error) {
+	return m.request(ctx, fiber.MethodGet, nil, fmt.Sprintf("%s/texture/%s", m.Hosts.Textures, textureid))
+}
+
+// request sends a request to the upstream api, retrying transient failures according to the Retry policy until ctx is done
+func (m *FasthttpMojangClient) request(ctx context.Context, method string, body []byte, url string) (int, []byte, []error) {
+	h := m.handler
+
+	for retry := 1; ; retry++ {
+		code, respBody, retryAfter, errs := m.attempt(ctx, method, body, url)
+
+		if retry >= m.Retry.MaxAttempts || !m.Retry.retryable(code, errs) || ctx.Err() != nil {
+			return code, respBody, errs
+		}
+
+		delay, ok := m.Retry.delay(retry, retryAfter)
+
+		//a retry that can only start after the deadline is not worth waiting for
+		if deadline, hasDeadline := ctx.Deadline(); hasDeadline && time.Now().Add(delay).After(deadline) {
+			ok = false
+		}
+
+		if !ok {
+			return code, respBody, errs
+		}
+
+		if h.Logger != nil {
+			h.Logger.Warn("Retrying %s in %v after %d %v (attempt %d of %d)", url, delay, code, errs, retry+1, m.Retry.MaxAttempts)
+		}
+
+		timer := time.NewTimer(delay)
+
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return contextStatus(ctx.Err()), nil, []error{ctx.Err()}
+		}
+	}
+}
+
+// timeouts returns the dial timeout and how long the next attempt may take, never past the deadline of ctx
+func (m *FasthttpMojangClient) timeouts(ctx context.Context) (time.Duration, time.Duration) {
+	dial, read := m.DialTimeout, m.ReadTimeout
+
+	if dial <= 0 {
+		dial = DefaultDialTimeout
+	}
+
+	if read <= 0 {
+		read = DefaultReadTimeout
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if remaining := time.Until(deadline); remaining < read {
+			read = remaining
+		}
+	}
+
+	if read < dial {
+		dial = read
+	}
+
+	return dial, read
+}
+
+// attempt sends a single request, dialing from the next ip in the IPPool and reporting a 429 on it to the Scheduler.
+// Transport failures are reported as 502, or 504 when they timed out
+func (m *FasthttpMojangClient) attempt(ctx context.Context, method string, body []byte, url string) (int, []byte, time.Duration, []error) {
+	h := m.handler
+
+	if err := ctx.Err(); err != nil {
+		return contextStatus(err), nil, 0, []error{err}
+	}
+
+	a := fiber.AcquireAgent()
+	req := a.Request()
+	req.Header.SetMethod(method)
+	req.SetRequestURI(url)
+
+	if body != nil {
+		a.ContentType(fiber.MIMEApplicationJSON)
+		a.Body(body)
+	}
+
+	if err := a.Parse(); err != nil {
+
+		if h.Logger != nil {
+			h.Logger.Error("%v", err)
+		}
+
+		return fiber.StatusInternalServerError, nil, 0, []error{err}
+	}
+
+	dialTimeout, readTimeout := m.timeouts(ctx)
+	a.Timeout(readTimeout)
+
+	ip := h.nextIP()
+	customDialer := fasthttp.TCPDialer{
+		Concurrency: 1000,
+	}
+
+	if ip != nil {
+		customDialer.LocalAddr = &net.TCPAddr{
+			IP: ip,
+		}
+
+		if h.Logger != nil {
+			h.Logger.Info("Dialing %s from %s", url, customDialer.LocalAddr.String())
+		}
+	}
+
+	a.HostClient.Dial = func(addr string) (net.Conn, error) {
+		return customDialer.DialTimeout(addr, dialTimeout)
+	}
+
+	type outcome struct {
+		code       int
+		body       []byte
+		retryAfter time.Duration
+		errs       []error
+	}
+
+	//fasthttp can not be cancelled, so the request is left to its timeout when ctx is done first
+	done := make(chan outcome, 1)
+
+	go func() {
+		//keep hold of the response to read the Retry-After header
+		resp := fiber.AcquireResponse()
+		defer fiber.ReleaseResponse(resp)
+		a.SetResponse(resp)
+
+		code, respBody, errs := a.Bytes()
+		retryAfter := parseRetryAfter(string(resp.Header.Peek(fiber.HeaderRetryAfter)), time.Now())
+
+		done <- outcome{code, respBody, retryAfter, errs}
+	}()
+
+	select {
+	case o := <-done:
+		if len(o.errs) > 0 {
+			return transportStatus(o.errs), nil, 0, o.errs
+		}
+
+		if o.code == fiber.StatusTooManyRequests {
+			h.rateLimited(ip, o.retryAfter)
+		}
+
+		return o.code, o.body, o.retryAfter, o.errs
+	case <-ctx.Done():
+		return contextStatus(ctx.Err()), nil, 0, []error{ctx.Err()}
+	}
+}
+
+// contextStatus the status reported when the context of a request ended it, 504 when its deadline passed
+func contextStatus(err error) int {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fiber.StatusGatewayTimeout
+	}
+
+	return fiber.StatusServiceUnavailable
+}
+
+// transportStatus the status reported for a request that got no response, 504 when it timed out and 502 otherwise
+func transportStatus(errs []error) int {
+	for _, err := range errs {
+		var netErr net.Error
+
+		if errors.Is(err, fasthttp.ErrTimeout) || errors.Is(err, fasthttp.ErrDialTimeout) || errors.Is(err, context.DeadlineExceeded) ||
+			(errors.As(err, &netErr) && netErr.Timeout()) {
+			return fiber.StatusGatewayTimeout
+		}
+	}
+
+	return fiber.StatusBadGateway
+}
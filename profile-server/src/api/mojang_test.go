@@ -0,0 +1,52 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestMojangClientHosts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/session/minecraft/profile/069a79f444e94726a5befca90e38aaf5":
+			if r.URL.Query().Get("unsigned") != "false" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			_, _ = w.Write([]byte(`{"id":"069a79f444e94726a5befca90e38aaf5","name":"Notch","properties":[]}`))
+		case "/profiles/minecraft":
+			if r.Method != http.MethodPost {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+
+			_, _ = w.Write([]byte(`[{"id":"069a79f444e94726a5befca90e38aaf5","name":"Notch"}]`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	handler := &Handler{}
+	handler.Mojang = NewMojangClient(handler, MojangHosts{SessionServer: server.URL + "/", API: server.URL, Textures: server.URL})
+
+	code, profile, _, errs := handler.FetchProfile(context.Background(), "069a79f444e94726a5befca90e38aaf5")
+	if code != fiber.StatusOK || len(errs) > 0 || profile.Name != "Notch" {
+		t.Fatalf("FetchProfile = %d %v %v", code, profile, errs)
+	}
+
+	code, usernames, _, errs := handler.FetchUUIDBatch(context.Background(), []string{"Notch"})
+	if code != fiber.StatusOK || len(errs) > 0 || len(usernames) != 1 || usernames[0].Name != "Notch" {
+		t.Fatalf("FetchUUIDBatch = %d %v %v", code, usernames, errs)
+	}
+
+	code, _, _, _ = handler.FetchTexture(context.Background(), "missing")
+	if code != fiber.StatusNotFound {
+		t.Fatalf("FetchTexture = %d, want 404", code)
+	}
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// DefaultNegativeTTL how long players, names and textures mojang reported missing are cached by default
+const DefaultNegativeTTL = 1 * time.Minute
+
+// missingUsername the cached value of a username mojang does not know, never valid UsernameResponse json
+const missingUsername = `{"missing":true}`
+
+// ErrorResponse body of error responses, shaped like the errors of the mojang api
+type ErrorResponse struct {
+	Error        string `json:"error"`
+	ErrorMessage string `json:"errorMessage"`
+}
+
+// isNotFound reports if an upstream status means there is nothing to find, the session server answers unknown players with 204
+func isNotFound(code int) bool {
+	return code == fiber.StatusNoContent || code == fiber.StatusNotFound
+}
+
+func (h *Handler) negativeTTL() time.Duration {
+	if h.NegativeTTL > 0 {
+		return h.NegativeTTL
+	}
+
+	return DefaultNegativeTTL
+}
+
+// missingWrite prepares a negative entry under key that expires after the NegativeTTL, nil when it can not be encoded
+func (h *Handler) missingWrite(key string) *cacheWrite {
+	out, err := json.Marshal(&cacheEntry{
+		StoredAt: time.Now().UnixMilli(),
+		Missing:  true,
+	})
+
+	if err != nil {
+		h.Logger.Error("[%s] Failed to cache missing entry: %v", key, err)
+		return nil
+	}
+
+	return &cacheWrite{key, string(out), h.negativeTTL()}
+}
+
+// cacheMissing stores a negative entry under key that expires after the NegativeTTL
+func (h *Handler) cacheMissing(ctx context.Context, key string) {
+	write := h.missingWrite(key)
+
+	if write == nil {
+		return
+	}
+
+	err := h.CachePut(ctx, write.key, write.value, write.ttl)
+	if err != nil {
+		h.Logger.Error("[%s] Failed to cache missing entry: %v", key, err)
+	}
+}
+
+// sendNotFound responds with a 404 error body, clients may remember it for the NegativeTTL
+func (h *Handler) sendNotFound(c *fiber.Ctx, format string, args ...interface{}) error {
+	c.Status(fiber.StatusNotFound)
+	c.Set(fiber.HeaderCacheControl, fmt.Sprintf("private, max-age=%d", int(h.negativeTTL().Seconds())))
+
+	return c.JSON(&ErrorResponse{
+		Error:        "NotFound",
+		ErrorMessage: fmt.Sprintf(format, args...),
+	})
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v9"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestNegativeCache(t *testing.T) {
+	handler, server := newFakeHandler(t)
+
+	mr := miniredis.RunT(t)
+	handler.Rdb = redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	handler.Ctx = context.Background()
+	handler.NegativeTTL = 30 * time.Second
+
+	app := fiber.New()
+	app.Get("/profile/:uuid", handler.GetProfile)
+	app.Get("/texture/:textureid", handler.GetTexture)
+	app.Get("/uuid/:username", handler.GetUUID)
+
+	missingUUID := "00000000000040008000000000000000"
+
+	for _, path := range []string{"/profile/" + missingUUID, "/texture/deadbeef", "/uuid/nobody_here"} {
+		before := server.Requests()
+
+		//the second request is answered from the negative entry without asking the upstream
+		for i := 0; i < 2; i++ {
+			res, err := app.Test(httptest.NewRequest(fiber.MethodGet, path, nil), -1)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if res.StatusCode != fiber.StatusNotFound {
+				t.Fatalf("GET %s = %d, want 404", path, res.StatusCode)
+			}
+
+			if res.Header.Get(fiber.HeaderCacheControl) != "private, max-age=30" {
+				t.Errorf("GET %s Cache-Control = %s", path, res.Header.Get(fiber.HeaderCacheControl))
+			}
+
+			body := &ErrorResponse{}
+			if err := json.NewDecoder(res.Body).Decode(body); err != nil || body.Error != "NotFound" {
+				t.Errorf("GET %s body = %+v %v", path, body, err)
+			}
+		}
+
+		if server.Requests()-before != 1 {
+			t.Errorf("GET %s twice made %d upstream requests, want 1", path, server.Requests()-before)
+		}
+	}
+
+	//negative entries expire after the NegativeTTL
+	if ttl := mr.TTL(profileKey(UUID(missingUUID))); ttl <= 0 || ttl > handler.NegativeTTL {
+		t.Errorf("negative entry ttl = %v, want at most %v", ttl, handler.NegativeTTL)
+	}
+
+	//a negative entry is not mistaken for a real profile
+	entry, err := handler.cacheGetEntry(context.Background(), profileKey(UUID(missingUUID)))
+	if err != nil || entry == nil || !entry.Missing || entry.Value != "" {
+		t.Errorf("negative entry = %+v %v", entry, err)
+	}
+}
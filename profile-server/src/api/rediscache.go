@@ -0,0 +1,274 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v9"
+)
+
+// ErrInvalidation wraps the failure to tell the other replicas about a write that did succeed,
+// their local copies of the key stay stale until they expire
+var ErrInvalidation = errors.New("failed to invalidate local copies")
+
+// invalidationError wraps err in ErrInvalidation, so callers can tell it apart from a failed write
+func invalidationError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %v", ErrInvalidation, err)
+}
+
+// unchanged reports if writing value over old leaves what the other replicas serve as is, so their copies can stay.
+// Cache entries differ in the time they were stored on every write, which only does not matter for immutable keys
+func unchanged(key string, old string, value string) bool {
+	if old == value {
+		return true
+	}
+
+	if !isImmutable(key) {
+		return false
+	}
+
+	var before, after cacheEntry
+	if json.Unmarshal([]byte(old), &before) != nil || json.Unmarshal([]byte(value), &after) != nil {
+		return false
+	}
+
+	//values that are not entries decode without the time they were stored
+	if before.StoredAt == 0 || after.StoredAt == 0 {
+		return false
+	}
+
+	return before.Value == after.Value && before.Missing == after.Missing
+}
+
+// RedisCache a Cache shared by every replica through redis
+type RedisCache struct {
+	Rdb *redis.Client
+	// Local optional in-process tier in front of Rdb, kept consistent across replicas through redis pub/sub
+	Local *LocalCache
+}
+
+// NewRedisCache creates a RedisCache without an in-process tier
+func NewRedisCache(rdb *redis.Client) *RedisCache {
+	return &RedisCache{Rdb: rdb}
+}
+
+func (r *RedisCache) Get(ctx context.Context, key string) (bool, string, error) {
+	if r.Local != nil {
+		if item, ok := r.Local.Get(key); ok {
+			return true, item, nil
+		}
+
+		return r.getRemote(ctx, key)
+	}
+
+	item, err := r.Rdb.Get(ctx, key).Result()
+
+	switch err {
+	case redis.Nil:
+		//key does not exist in cache
+		return false, "", nil
+	case nil:
+		//key does exist in cache
+		return true, item, nil
+	default:
+		return false, "", err
+	}
+}
+
+// getRemote reads key from redis together with its remaining ttl and keeps a local copy of it
+func (r *RedisCache) getRemote(ctx context.Context, key string) (bool, string, error) {
+	pipe := r.Rdb.Pipeline()
+	get := pipe.Get(ctx, key)
+	pttl := pipe.PTTL(ctx, key)
+	_, err := pipe.Exec(ctx)
+
+	switch err {
+	case redis.Nil:
+		//key does not exist in cache
+		return false, "", nil
+	case nil:
+		//key does exist in cache, the local copy must not outlive it
+		r.Local.Put(key, get.Val(), pttl.Val())
+		return true, get.Val(), nil
+	default:
+		return false, "", err
+	}
+}
+
+func (r *RedisCache) Put(ctx context.Context, key string, value string, ttl time.Duration) error {
+	if r.Local == nil {
+		return r.Rdb.Set(ctx, key, value, ttl).Err()
+	}
+
+	//read the replaced value in the same call, the copies of the other replicas only go stale if it changed
+	old, err := r.Rdb.SetArgs(ctx, key, value, redis.SetArgs{TTL: ttl, Get: true}).Result()
+
+	if err != nil && err != redis.Nil {
+		return err
+	}
+
+	r.Local.Put(key, value, ttl)
+
+	if err == nil && unchanged(key, old, value) {
+		return nil
+	}
+
+	//the other replicas drop their now outdated copy
+	return invalidationError(r.Local.publish(ctx, r.Rdb, key))
+}
+
+func (r *RedisCache) MultiPut(ctx context.Context, items map[string]string, ttl time.Duration) error {
+	pipe := r.Rdb.Pipeline()
+
+	if r.Local == nil {
+		for key, value := range items {
+			pipe.Set(ctx, key, value, ttl)
+		}
+
+		_, err := pipe.Exec(ctx)
+		return err
+	}
+
+	sets := make(map[string]*redis.StatusCmd, len(items))
+	for key, value := range items {
+		sets[key] = pipe.SetArgs(ctx, key, value, redis.SetArgs{TTL: ttl, Get: true})
+	}
+
+	//keys that did not exist before come back as redis.Nil, which is checked per command below
+	_, _ = pipe.Exec(ctx)
+
+	publish := r.Rdb.Pipeline()
+
+	for key, value := range items {
+		old, err := sets[key].Result()
+
+		if err != nil && err != redis.Nil {
+			return err
+		}
+
+		r.Local.Put(key, value, ttl)
+
+		//the other replicas drop their copy of the values that changed
+		if err == redis.Nil || !unchanged(key, old, value) {
+			r.Local.publish(ctx, publish, key)
+		}
+	}
+
+	if publish.Len() == 0 {
+		return nil
+	}
+
+	_, err := publish.Exec(ctx)
+	return invalidationError(err)
+}
+
+func (r *RedisCache) MultiGet(ctx context.Context, keys []string) (map[string]string, error) {
+	items := make(map[string]string, len(keys))
+	var missing []string
+
+	for _, key := range keys {
+		if r.Local != nil {
+			if item, ok := r.Local.Get(key); ok {
+				items[key] = item
+				continue
+			}
+		}
+
+		missing = append(missing, key)
+	}
+
+	if len(missing) == 0 {
+		return items, nil
+	}
+
+	if r.Local != nil {
+		//local copies must not outlive their key, so the remaining ttls are read along in the same round trip
+		pipe := r.Rdb.Pipeline()
+		gets := make([]*redis.StringCmd, len(missing))
+		pttls := make([]*redis.DurationCmd, len(missing))
+
+		for i, key := range missing {
+			gets[i] = pipe.Get(ctx, key)
+			pttls[i] = pipe.PTTL(ctx, key)
+		}
+
+		//missing keys fail with redis.Nil, so the error of every command is checked on its own
+		_, _ = pipe.Exec(ctx)
+
+		for i, key := range missing {
+			if err := gets[i].Err(); err == redis.Nil {
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+
+			items[key] = gets[i].Val()
+			r.Local.Put(key, gets[i].Val(), pttls[i].Val())
+		}
+
+		return items, nil
+	}
+
+	values, err := r.Rdb.MGet(ctx, missing...).Result()
+
+	if err != nil {
+		return nil, err
+	}
+
+	for i, value := range values {
+		//keys that do not exist come back as nil
+		if item, ok := value.(string); ok {
+			items[missing[i]] = item
+		}
+	}
+
+	return items, nil
+}
+
+func (r *RedisCache) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	err := r.Rdb.Del(ctx, keys...).Err()
+
+	if err != nil || r.Local == nil {
+		return err
+	}
+
+	publish := r.Rdb.Pipeline()
+
+	for _, key := range keys {
+		r.Local.Delete(key)
+		r.Local.publish(ctx, publish, key)
+	}
+
+	_, err = publish.Exec(ctx)
+	return invalidationError(err)
+}
+
+func (r *RedisCache) TTL(ctx context.Context, key string) (bool, time.Duration, error) {
+	ttl, err := r.Rdb.PTTL(ctx, key).Result()
+
+	if err != nil {
+		return false, 0, err
+	}
+
+	switch {
+	case ttl == -2:
+		//key does not exist in cache
+		return false, 0, nil
+	case ttl < 0:
+		//key never expires
+		return true, 0, nil
+	default:
+		return true, ttl, nil
+	}
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// DefaultRetryPolicy retries transient upstream failures a few times within about a second
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:          3,
+	BaseDelay:            100 * time.Millisecond,
+	MaxDelay:             2 * time.Second,
+	Jitter:               0.5,
+	RetryServerErrors:    true,
+	RetryTooManyRequests: true,
+	MaxRetryAfter:        5 * time.Second,
+}
+
+// RetryPolicy how often and how long to wait before repeating a failed upstream request
+type RetryPolicy struct {
+	// MaxAttempts total number of attempts including the first one, values below 1 make a single attempt
+	MaxAttempts int
+	// BaseDelay wait before the first retry, doubled on every further retry
+	BaseDelay time.Duration
+	// MaxDelay caps the exponential backoff
+	MaxDelay time.Duration
+	// Jitter fraction of the delay that is randomized, spreading out retries of concurrent requests
+	Jitter float64
+	// RetryServerErrors retries 5xx responses
+	RetryServerErrors bool
+	// RetryTooManyRequests retries 429 responses
+	RetryTooManyRequests bool
+	// MaxRetryAfter longest Retry-After header that is honored, longer waits give up instead
+	MaxRetryAfter time.Duration
+}
+
+// NoRetryPolicy makes a single attempt
+var NoRetryPolicy = RetryPolicy{MaxAttempts: 1}
+
+// retryable reports if the outcome of an attempt is worth repeating, transport errors always are
+func (p RetryPolicy) retryable(code int, errs []error) bool {
+	if len(errs) > 0 {
+		return true
+	}
+
+	switch {
+	case code == fiber.StatusTooManyRequests:
+		return p.RetryTooManyRequests
+	case code >= 500:
+		return p.RetryServerErrors
+	}
+
+	return false
+}
+
+// delay returns how long to wait before the given retry, starting at 1, and if the retry should happen at all
+func (p RetryPolicy) delay(retry int, retryAfter time.Duration) (time.Duration, bool) {
+	//the upstream told us how long to back off
+	if retryAfter > 0 {
+		if p.MaxRetryAfter > 0 && retryAfter > p.MaxRetryAfter {
+			return 0, false
+		}
+
+		return retryAfter, true
+	}
+
+	d := float64(p.BaseDelay) * math.Pow(2, float64(retry-1))
+
+	if p.MaxDelay > 0 && d > float64(p.MaxDelay) {
+		d = float64(p.MaxDelay)
+	}
+
+	if p.Jitter > 0 {
+		jitter := math.Min(p.Jitter, 1)
+		d = d*(1-jitter) + rand.Float64()*d*jitter
+	}
+
+	return time.Duration(d), true
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an http date
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+
+	return 0
+}
@@ -1,90 +1,138 @@
 package api
 
 import (
+	"bytes"
+	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"github.com/go-redis/redis/v9"
+	"image"
+	"image/png"
+
+	"bed.gg/minecraft-api/v2/src/motd"
+	"bed.gg/minecraft-api/v2/src/render"
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 	"github.com/meilisearch/meilisearch-go"
+	"strconv"
+	"strings"
 	"time"
 )
 
 const TTL = 15 * time.Minute
 
+// TextureTTL textures never change under their id, so they are kept far longer than the profiles pointing at them
+const TextureTTL = 30 * 24 * time.Hour
+
+// UsernameTTL usernames can change at most every 30 days, so the mapping is cached longer than profiles
+const UsernameTTL = 1 * time.Hour
+
+// RenderTTL renders are keyed by texture id and never change, so they outlive the profile they were resolved from
+const RenderTTL = 24 * time.Hour
+
+// ServerTTL server status changes constantly, it is only cached to shield the pinged server from bursts
+const ServerTTL = 1 * time.Minute
+
+// ImmutableMaxAge textures are content addressed by their id, so clients may keep them for a year
+const ImmutableMaxAge = 365 * 24 * time.Hour
+
+// renderers flat renders available at /render/:type/:uuid
+var renderers = map[string]func(s *render.Skin, width int, overlay bool) *image.NRGBA{
+	"head": render.Head,
+	"face": render.Face,
+	"bust": render.Bust,
+	"body": render.Body,
+}
+
 func (h *Handler) GetProfile(c *fiber.Ctx) error {
-	playerUUID := c.Params("uuid")
+	ctx := c.UserContext()
+	rawUUID := c.Params("uuid")
 	remoteAddr := c.Context().Conn().RemoteAddr().String()
 
-	h.Logger.Info("%s GET /profile/%s", remoteAddr, playerUUID)
+	h.Logger.Info("%s GET /profile/%s", remoteAddr, rawUUID)
+
+	playerUUID, err := ParseUUID(rawUUID)
+	if err != nil {
+		c.Status(fiber.StatusBadRequest)
+		return c.SendString(fmt.Sprintf("bad uuid: %s", rawUUID))
+	}
 
-	if IsValidUUID(playerUUID) {
-		//check if the profile exists in redis already and is not expired
-		exists, item, err := h.CacheGet(playerUUID)
+	//resolve the profile through the cache, stale profiles are served while being refreshed
+	code, profileResponse, _, age, errs := h.lookupProfile(ctx, playerUUID)
 
-		//check if a redis error occurred
-		if err != nil {
-			return c.SendStatus(fiber.StatusInternalServerError)
+	//check if the profile was able to be resolved
+	if profileResponse == nil {
+		//log all the errors that occurred
+		for _, err := range errs {
+			if err != nil {
+				h.Logger.Error("%v", err)
+			}
 		}
 
-		//check if the cache was a hit or miss
-		if exists {
-			//cache hit
-			h.Logger.Info("[%s] Cache Hit for [%s]", playerUUID, remoteAddr)
-			c.Status(fiber.StatusOK)
-			c.Set(fiber.HeaderCacheControl, fmt.Sprintf("private, max-age=%d", int32(TTL.Seconds())))
-			return c.SendString(item)
-		} else {
-			//cache miss
-			h.Logger.Info("[%s] Cache Miss for %s", playerUUID, remoteAddr)
-			code, profileResponse, profileResponseString, errs := h.FetchProfile(playerUUID)
+		if code == fiber.StatusNotFound {
+			return h.sendNotFound(c, "no player with uuid %s", playerUUID)
+		}
 
-			//check if fetching the profile yielded any errors
-			if len(errs) > 0 {
-				//log all the errors that occurred
-				for _, err := range errs {
-					if err != nil {
-						h.Logger.Error("%v", err)
-					}
-				}
+		//return the error's status code
+		return c.SendStatus(code)
+	}
 
-				//return the error's status code
-				return c.SendStatus(code)
-			}
+	//attach the signature verification result
+	profileResponse.Verified = h.verified(profileResponse)
+	out, err := json.Marshal(profileResponse)
+	if err != nil {
+		h.Logger.Error("%v", err)
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
 
-			//check if the profile was able to be fetched
-			if profileResponse == nil {
-				//log all the errors that occurred
-				for _, err := range errs {
-					if err != nil {
-						h.Logger.Error("%v", err)
-					}
-				}
+	c.Status(fiber.StatusOK)
+	setCacheHeaders(c, age, TTL)
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	return c.Send(out)
+}
 
-				//return the error's status code
-				return c.SendStatus(code)
-			}
+func (h *Handler) GetDecodedProfile(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	rawUUID := c.Params("uuid")
+	remoteAddr := c.Context().Conn().RemoteAddr().String()
 
-			//cache the profile
-			err = h.CachePut(playerUUID, string(profileResponseString), TTL)
-			if err != nil {
-				h.Logger.Error("[%s] Failed to cache profile: %v", playerUUID, err)
-			}
+	h.Logger.Info("%s GET /profile/%s/decoded", remoteAddr, rawUUID)
 
-			c.Status(code)
+	playerUUID, err := ParseUUID(rawUUID)
+	if err != nil {
+		c.Status(fiber.StatusBadRequest)
+		return c.SendString(fmt.Sprintf("bad uuid: %s", rawUUID))
+	}
 
-			if code == fiber.StatusOK {
-				c.Set(fiber.HeaderCacheControl, fmt.Sprintf("private, max-age=%d", int32(TTL.Seconds())))
-			}
+	code, profileResponse, _, age, errs := h.lookupProfile(ctx, playerUUID)
+	if profileResponse == nil {
+		for _, err := range errs {
+			h.Logger.Error("%v", err)
+		}
 
-			return c.Send(profileResponseString)
+		if code == fiber.StatusNotFound {
+			return h.sendNotFound(c, "no player with uuid %s", playerUUID)
 		}
-	} else {
-		c.Status(fiber.StatusBadRequest)
-		return c.SendString(fmt.Sprintf("bad uuid: %s", playerUUID))
+
+		return c.SendStatus(code)
+	}
+
+	decodedProfile, err := profileResponse.Decode()
+	if err != nil {
+		h.Logger.Error("[%s] Failed to decode textures: %v", playerUUID, err)
+		return c.SendStatus(fiber.StatusBadGateway)
 	}
+
+	decodedProfile.Verified = h.verified(profileResponse)
+
+	c.Status(fiber.StatusOK)
+	setCacheHeaders(c, age, TTL)
+	return c.JSON(decodedProfile)
 }
 
 func (h *Handler) GetProfiles(c *fiber.Ctx) error {
+	ctx := c.UserContext()
 	remoteAddr := c.Context().Conn().RemoteAddr().String()
 	uuidsBody := new(UUIDSBody)
 
@@ -95,90 +143,98 @@ func (h *Handler) GetProfiles(c *fiber.Ctx) error {
 		return err
 	}
 
-	uuids := uuidsBody.UUIDS
+	if err := h.checkBatchSize(len(uuidsBody.UUIDS)); err != nil {
+		return err
+	}
+
+	//check if all uuids are valid, dropping duplicates of the same player
+	var uuids []UUID
+	seen := make(map[UUID]bool)
 
-	//check if all uuids are valid
-	for _, playerUUID := range uuids {
-		if !IsValidUUID(playerUUID) {
-			return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("bad uuid: %s", playerUUID))
+	for _, rawUUID := range uuidsBody.UUIDS {
+		playerUUID, err := ParseUUID(rawUUID)
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("bad uuid: %s", rawUUID))
+		}
+
+		if !seen[playerUUID] {
+			seen[playerUUID] = true
+			uuids = append(uuids, playerUUID)
 		}
 	}
 
-	//output array
-	var profileBodyArray []*ProfileResponse
-	var nonCachedUUIDS []string
+	legacy, err := isLegacyBatch(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
 
-	//check for cached uuids
-	for _, playerUUID := range uuids {
-		exists, item, err := h.CacheGet(playerUUID)
+	//resolve every profile through the cache, hits are read and misses written back in a single round trip
+	batchCtx, cancel := h.batchContext(ctx)
+	defer cancel()
 
-		//check if a redis error occured
-		if err != nil {
-			return c.SendStatus(fiber.StatusInternalServerError)
-		}
+	lookups := h.lookupProfiles(batchCtx, uuids)
 
-		if exists {
-			//cache hit
-			h.Logger.Info("[%s] Cache Hit for [%s]", playerUUID, remoteAddr)
+	if legacy {
+		return h.sendProfilesArray(c, uuids, lookups)
+	}
 
-			//add the output to the array
-			decodedProfileResponse := &ProfileResponse{}
-			err := json.Unmarshal([]byte(item), decodedProfileResponse)
-			if err != nil {
-				h.Logger.Error("Failed to unmarshall profile!")
-				return c.SendStatus(fiber.StatusInternalServerError)
+	//every uuid is reported on its own, a player that failed does not fail the others
+	response := newBatchResponse()
+	var oldest time.Duration
+
+	for i, lookup := range lookups {
+		id := string(uuids[i])
+
+		if len(lookup.errs) > 0 || lookup.profile == nil {
+			for _, err := range lookup.errs {
+				h.Logger.Error("[%s] %v", id, err)
 			}
 
-			//aggregate cached ProfileResponses
-			profileBodyArray = append(profileBodyArray, decodedProfileResponse)
-		} else {
-			//cache miss
-			h.Logger.Info("[%s] Cache Miss for %s", playerUUID, remoteAddr)
+			response.Errors.Set(id, batchError(lookup.code, lookup.errs, "no player with uuid %s", id))
+			continue
+		}
 
-			//add non-cached uuids to be looked up
-			nonCachedUUIDS = append(nonCachedUUIDS, playerUUID)
+		lookup.profile.Verified = h.verified(lookup.profile)
+		response.Results.Set(id, lookup.profile)
+
+		if lookup.age > oldest {
+			oldest = lookup.age
 		}
 	}
 
-	if len(nonCachedUUIDS) > 0 {
-		//lookup non-cached uuids
-		responses := h.FetchProfiles(nonCachedUUIDS)
+	return h.sendBatch(c, response, oldest, TTL)
+}
 
-		//check for errors in multi-fetch
-		for _, response := range responses {
-			if len(response.Errs) > 0 {
-				for _, err := range response.Errs {
-					h.Logger.Error("%v", err)
-				}
+// sendProfilesArray responds with the legacy array of profiles, failing as a whole when any lookup failed
+func (h *Handler) sendProfilesArray(c *fiber.Ctx, uuids []UUID, lookups []profileLookup) error {
+	//output array
+	var profileBodyArray []*ProfileResponse
+	var oldest time.Duration
 
-				return c.SendStatus(fiber.StatusInternalServerError)
+	for i, lookup := range lookups {
+		//check for errors in multi-lookup
+		if len(lookup.errs) > 0 || lookup.profile == nil {
+			for _, err := range lookup.errs {
+				h.Logger.Error("%v", err)
 			}
 
-			if response.Profile == nil {
-				for _, err := range response.Errs {
-					h.Logger.Error("%v", err)
-				}
-
+			if lookup.code == fiber.StatusOK || len(lookup.errs) > 0 {
 				return c.SendStatus(fiber.StatusInternalServerError)
 			}
 
-			if response.Code != fiber.StatusOK {
-				for _, err := range response.Errs {
-					h.Logger.Error("%v", err)
-				}
-
-				return c.SendStatus(response.Code)
+			if lookup.code == fiber.StatusNotFound {
+				return h.sendNotFound(c, "no player with uuid %s", uuids[i])
 			}
+
+			return c.SendStatus(lookup.code)
 		}
 
-		//aggregate new responses and cache them
-		for _, response := range responses {
-			profileBodyArray = append(profileBodyArray, response.Profile)
+		//aggregate the ProfileResponses
+		lookup.profile.Verified = h.verified(lookup.profile)
+		profileBodyArray = append(profileBodyArray, lookup.profile)
 
-			err := h.CachePut(response.Id, string(response.Body), TTL)
-			if err != nil {
-				h.Logger.Error("[%s] Failed to cache profile: %v", response.Id, err)
-			}
+		if lookup.age > oldest {
+			oldest = lookup.age
 		}
 	}
 
@@ -190,25 +246,270 @@ func (h *Handler) GetProfiles(c *fiber.Ctx) error {
 	}
 
 	c.Status(200)
-	c.Set(fiber.HeaderCacheControl, fmt.Sprintf("private, max-age=%d", int32(TTL.Seconds())))
+	setCacheHeaders(c, oldest, TTL)
 	return c.Send(out)
 }
 
 func (h *Handler) GetTexture(c *fiber.Ctx) error {
+	ctx := c.UserContext()
 	textureid := c.Params("textureid")
 	remoteAddr := c.Context().Conn().RemoteAddr().String()
 
 	if isValidTextureId(textureid) {
-		//check if the texture exists in redis already and is not expired
-		item, err := h.Rdb.Get(h.Ctx, textureid).Result()
+		//clients asking for an image get the raw png instead of the base64 string
+		c.Vary(fiber.HeaderAccept)
+		if c.Accepts(fiber.MIMETextPlain, "image/png") == "image/png" {
+			return h.sendTexturePNG(c, textureid)
+		}
+
+		//resolve the texture through the cache, textures never change so they are not refreshed
+		h.Logger.Info("[%s] Texture for %s", textureid, remoteAddr)
+		code, body, age, errs := h.lookupTexture(ctx, textureid)
+
+		//check if the texture was able to be resolved
+		if body == nil {
+			//log all the errors that occurred
+			for _, err := range errs {
+				if err != nil {
+					h.Logger.Error("%v", err)
+				}
+			}
+
+			if code == fiber.StatusNotFound {
+				return h.sendNotFound(c, "no texture with id %s", textureid)
+			}
+
+			//return the error's status code
+			return c.SendStatus(code)
+		}
+
+		c.Status(fiber.StatusOK)
+		setCacheHeaders(c, age, TextureTTL)
+		return c.SendString(base64.StdEncoding.EncodeToString(body))
+	} else {
+		c.Status(fiber.StatusBadRequest)
+		return c.SendString(fmt.Sprintf("bad skinid: %s", textureid))
+	}
+}
+
+func (h *Handler) GetTexturePNG(c *fiber.Ctx) error {
+	textureid := c.Params("textureid")
+
+	if !isValidTextureId(textureid) {
+		c.Status(fiber.StatusBadRequest)
+		return c.SendString(fmt.Sprintf("bad skinid: %s", textureid))
+	}
+
+	return h.sendTexturePNG(c, textureid)
+}
+
+// sendTexturePNG responds with the raw texture png, textures never change so the id doubles as a strong etag
+func (h *Handler) sendTexturePNG(c *fiber.Ctx, textureid string) error {
+	ctx := c.UserContext()
+	remoteAddr := c.Context().Conn().RemoteAddr().String()
+	etag := fmt.Sprintf("\"%s\"", strings.ToLower(textureid))
+
+	c.Set(fiber.HeaderETag, etag)
+	c.Set(fiber.HeaderCacheControl, fmt.Sprintf("public, max-age=%d, immutable", int64(ImmutableMaxAge.Seconds())))
+
+	//the client already holds this texture
+	if etagMatches(c.Get(fiber.HeaderIfNoneMatch), etag) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	h.Logger.Info("[%s] PNG texture for %s", textureid, remoteAddr)
+	code, body, _, errs := h.lookupTexture(ctx, textureid)
 
-		switch err {
-		case redis.Nil:
-			//profile does not exist, fetch the profile
-			h.Logger.Info("[%s] Cache Miss for %s", textureid, remoteAddr)
-			code, textureBase64, _, errs := h.FetchTexture(textureid)
+	if body == nil {
+		for _, err := range errs {
+			h.Logger.Error("%v", err)
+		}
+
+		c.Response().Header.Del(fiber.HeaderETag)
+		c.Response().Header.Del(fiber.HeaderCacheControl)
+
+		if code == fiber.StatusNotFound {
+			return h.sendNotFound(c, "no texture with id %s", textureid)
+		}
+
+		return c.SendStatus(code)
+	}
+
+	c.Set(fiber.HeaderContentType, "image/png")
+	c.Status(fiber.StatusOK)
+	return c.Send(body)
+}
+
+// etagMatches reports if an If-None-Match header lists the etag, comparing weakly as If-None-Match requires
+func etagMatches(ifNoneMatch string, etag string) bool {
+	if strings.TrimSpace(ifNoneMatch) == "*" {
+		return true
+	}
+
+	etag = strings.TrimPrefix(etag, "W/")
+
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(tag), "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (h *Handler) GetTextures(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	remoteAddr := c.Context().Conn().RemoteAddr().String()
+	texturesBody := new(TexturesBody)
+
+	//parse the textureids array
+	if err := c.BodyParser(texturesBody); err != nil {
+		h.Logger.Error("Failed to parse body: %v from %s", texturesBody, remoteAddr)
+		c.Status(fiber.StatusInternalServerError)
+		return err
+	}
+
+	if err := h.checkBatchSize(len(texturesBody.Textures)); err != nil {
+		return err
+	}
+
+	//check if all textureids are valid, dropping duplicates of the same hex id
+	var textureids []string
+	seen := make(map[string]bool)
+
+	for _, textureid := range texturesBody.Textures {
+		if !isValidTextureId(textureid) {
+			return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("bad textureid: %s", textureid))
+		}
+
+		if !seen[strings.ToLower(textureid)] {
+			seen[strings.ToLower(textureid)] = true
+			textureids = append(textureids, textureid)
+		}
+	}
+
+	legacy, err := isLegacyBatch(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+
+	//resolve every texture through the cache, hits are read and misses written back in a single round trip
+	batchCtx, cancel := h.batchContext(ctx)
+	defer cancel()
+
+	lookups := h.lookupTextureIds(batchCtx, textureids)
+
+	if legacy {
+		return h.sendTexturesArray(c, textureids, lookups)
+	}
 
-			//check if fetching the profile yielded any errors
+	//every texture is reported on its own, a texture that failed does not fail the others
+	response := newBatchResponse()
+	var oldest time.Duration
+
+	for i, lookup := range lookups {
+		id := textureids[i]
+
+		if len(lookup.errs) > 0 || lookup.body == nil {
+			for _, err := range lookup.errs {
+				h.Logger.Error("[%s] %v", id, err)
+			}
+
+			response.Errors.Set(id, batchError(lookup.code, lookup.errs, "no texture with id %s", id))
+			continue
+		}
+
+		response.Results.Set(id, base64.StdEncoding.EncodeToString(lookup.body))
+
+		if lookup.age > oldest {
+			oldest = lookup.age
+		}
+	}
+
+	return h.sendBatch(c, response, oldest, TextureTTL)
+}
+
+// sendTexturesArray responds with the legacy array of base64 textures, failing as a whole when any lookup failed
+func (h *Handler) sendTexturesArray(c *fiber.Ctx, textureids []string, lookups []textureLookup) error {
+	//output array
+	var base64TextureArray []string
+	var oldest time.Duration
+
+	for i, lookup := range lookups {
+		//check for errors in multi-lookup
+		if len(lookup.errs) > 0 || lookup.body == nil {
+			for _, err := range lookup.errs {
+				h.Logger.Error("%v", err)
+			}
+
+			if lookup.code == fiber.StatusOK || len(lookup.errs) > 0 {
+				return c.SendStatus(fiber.StatusInternalServerError)
+			}
+
+			if lookup.code == fiber.StatusNotFound {
+				return h.sendNotFound(c, "no texture with id %s", textureids[i])
+			}
+
+			return c.SendStatus(lookup.code)
+		}
+
+		//aggregate the base64 textures
+		base64TextureArray = append(base64TextureArray, base64.StdEncoding.EncodeToString(lookup.body))
+
+		if lookup.age > oldest {
+			oldest = lookup.age
+		}
+	}
+
+	out, err := json.Marshal(base64TextureArray)
+	if err != nil {
+		c.Status(fiber.StatusInternalServerError)
+		h.Logger.Error("%v", err)
+		return fiber.NewError(fiber.StatusInternalServerError, "json Marhsal for mojang response failed")
+	}
+
+	setCacheHeaders(c, oldest, TextureTTL)
+	c.Status(fiber.StatusOK)
+	return c.Send(out)
+}
+
+func (h *Handler) GetUUID(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	username := c.Params("username")
+	remoteAddr := c.Context().Conn().RemoteAddr().String()
+
+	h.Logger.Info("%s GET %s", remoteAddr, c.Path())
+
+	if isValidUsername(username) {
+		//usernames are case-insensitive, so cache them under their lowercase form
+		key := usernameKey(username)
+
+		//check if the username exists in redis already and is not expired
+		exists, item, err := h.CacheGet(ctx, key)
+
+		//check if a redis error occurred
+		if err != nil {
+			return c.SendStatus(fiber.StatusInternalServerError)
+		}
+
+		//check if the cache was a hit or miss
+		if exists && item == missingUsername {
+			//negative cache hit, mojang recently reported the username as unknown
+			h.Logger.Info("[%s] Cache Hit, missing for [%s]", username, remoteAddr)
+			return h.sendNotFound(c, "no player with username %s", username)
+		} else if exists {
+			//cache hit
+			h.Logger.Info("[%s] Cache Hit for [%s]", username, remoteAddr)
+			c.Status(fiber.StatusOK)
+			c.Set(fiber.HeaderCacheControl, fmt.Sprintf("private, max-age=%d", int32(UsernameTTL.Seconds())))
+			c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+			return c.SendString(item)
+		} else {
+			//cache miss
+			h.Logger.Info("[%s] Cache Miss for %s", username, remoteAddr)
+			code, usernameResponse, usernameResponseString, errs := h.FetchUUID(ctx, username)
+
+			//check if fetching the uuid yielded any errors
 			if len(errs) > 0 {
 				//log all the errors that occurred
 				for _, err := range errs {
@@ -221,158 +522,516 @@ func (h *Handler) GetTexture(c *fiber.Ctx) error {
 				return c.SendStatus(code)
 			}
 
-			//check if the profile was able to be fetched
-			if textureBase64 == "" {
-				//log all the errors that occurred
-				for _, err := range errs {
+			//check if the uuid was able to be fetched
+			if usernameResponse == nil {
+				if isNotFound(code) {
+					err = h.CachePut(ctx, key, missingUsername, h.negativeTTL())
 					if err != nil {
-						h.Logger.Error("%v", err)
+						h.Logger.Error("[%s] Failed to cache missing username: %v", username, err)
 					}
+
+					return h.sendNotFound(c, "no player with username %s", username)
 				}
 
 				//return the error's status code
 				return c.SendStatus(code)
 			}
 
-			//cache the profile
-			err = h.Rdb.Set(h.Ctx, textureid, textureBase64, TTL).Err()
-
+			//cache the username
+			err = h.CachePut(ctx, key, string(usernameResponseString), UsernameTTL)
 			if err != nil {
-				h.Logger.Error("[%s] Failed to cache texture: %v", textureid, err)
+				h.Logger.Error("[%s] Failed to cache username: %v", username, err)
 			}
 
 			c.Status(code)
+			c.Set(fiber.HeaderCacheControl, fmt.Sprintf("private, max-age=%d", int32(UsernameTTL.Seconds())))
+			c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+			return c.Send(usernameResponseString)
+		}
+	} else {
+		c.Status(fiber.StatusBadRequest)
+		return c.SendString(fmt.Sprintf("bad username: %s", username))
+	}
+}
 
-			if code == fiber.StatusOK {
-				c.Set(fiber.HeaderCacheControl, fmt.Sprintf("private, max-age=%d", int32(TTL.Seconds())))
-			}
+func (h *Handler) GetOfflineUUID(c *fiber.Ctx) error {
+	username := c.Params("username")
+	remoteAddr := c.Context().Conn().RemoteAddr().String()
 
-			return c.SendString(textureBase64)
+	h.Logger.Info("%s GET /uuid/offline/%s", remoteAddr, username)
 
-		case nil:
-			h.Logger.Info("[%s] Cache Hit for [%s]", textureid, remoteAddr)
-			c.Set(fiber.HeaderCacheControl, fmt.Sprintf("private, max-age=%d", int32(TTL.Seconds())))
-			c.Status(fiber.StatusOK)
-			return c.SendString(item)
+	if !isValidUsername(username) {
+		c.Status(fiber.StatusBadRequest)
+		return c.SendString(fmt.Sprintf("bad username: %s", username))
+	}
 
-		default:
-			//some redis error occurred during cache lookup
-			h.Logger.Error("%v", err)
-			return c.SendStatus(fiber.StatusInternalServerError)
-		}
-	} else {
+	//offline uuids are derived from the exact username, so they never change
+	offlineUUID := formatUUID(OfflineUUID(username))
+
+	c.Status(fiber.StatusOK)
+	c.Set(fiber.HeaderCacheControl, fmt.Sprintf("public, max-age=%d, immutable", int64(ImmutableMaxAge.Seconds())))
+	return c.JSON(&OfflineUUIDResponse{
+		Name:   username,
+		Id:     offlineUUID.Id,
+		Dashed: offlineUUID.Dashed,
+	})
+}
+
+func (h *Handler) GetUUIDFormat(c *fiber.Ctx) error {
+	playerUUID := c.Params("uuid")
+
+	parsed, err := uuid.Parse(playerUUID)
+	if err != nil {
 		c.Status(fiber.StatusBadRequest)
-		return c.SendString(fmt.Sprintf("bad skinid: %s", textureid))
+		return c.SendString(fmt.Sprintf("bad uuid: %s", playerUUID))
 	}
+
+	c.Status(fiber.StatusOK)
+	c.Set(fiber.HeaderCacheControl, fmt.Sprintf("public, max-age=%d, immutable", int64(ImmutableMaxAge.Seconds())))
+	return c.JSON(formatUUID(parsed))
 }
 
-func (h *Handler) GetTextures(c *fiber.Ctx) error {
+func (h *Handler) PostUUIDs(c *fiber.Ctx) error {
+	ctx := c.UserContext()
 	remoteAddr := c.Context().Conn().RemoteAddr().String()
-	texturesBody := new(TexturesBody)
+	usernamesBody := new(UsernamesBody)
 
-	//parse the textureids array
-	if err := c.BodyParser(texturesBody); err != nil {
-		h.Logger.Error("Failed to parse body: %v from %s", texturesBody, remoteAddr)
+	//parse the usernames array
+	if err := c.BodyParser(usernamesBody); err != nil {
+		h.Logger.Error("Failed to parse body: %v from %s", usernamesBody, remoteAddr)
 		c.Status(fiber.StatusInternalServerError)
 		return err
 	}
 
-	textureids := texturesBody.Textures
+	if err := h.checkBatchSize(len(usernamesBody.Usernames)); err != nil {
+		return err
+	}
 
-	//check if all textureids are valid
-	for _, textureid := range textureids {
-		if !isValidTextureId(textureid) {
-			return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("bad textureid: %s", textureid))
+	//check if all usernames are valid, dropping case-insensitive duplicates
+	var usernames []string
+	seen := make(map[string]bool)
+
+	for _, username := range usernamesBody.Usernames {
+		if !isValidUsername(username) {
+			return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("bad username: %s", username))
+		}
+
+		if !seen[strings.ToLower(username)] {
+			seen[strings.ToLower(username)] = true
+			usernames = append(usernames, username)
 		}
 	}
 
-	//output array
-	var base64TextureArray []string
-	var nonCachedTextureIds []string
+	legacy, err := isLegacyBatch(c)
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
 
-	//check for cached textureids
-	for _, textureid := range textureids {
-		exists, item, err := h.CacheGet(textureid)
+	//resolve every username through the cache, the misses are looked up in batches
+	batchCtx, cancel := h.batchContext(ctx)
+	defer cancel()
 
-		//check if a redis error occured
-		if err != nil {
+	lookups := h.lookupUsernames(batchCtx, usernames)
+
+	if legacy {
+		return h.sendUsernamesArray(c, lookups)
+	}
+
+	//every username is reported on its own, a batch that failed does not fail the others
+	response := newBatchResponse()
+
+	for i, lookup := range lookups {
+		username := usernames[i]
+
+		if lookup.username == nil {
+			for _, err := range lookup.errs {
+				h.Logger.Error("[%s] %v", username, err)
+			}
+
+			response.Errors.Set(username, batchError(lookup.code, lookup.errs, "no player with username %s", username))
+			continue
+		}
+
+		response.Results.Set(username, lookup.username)
+	}
+
+	if response.Errors.Len() > 0 {
+		c.Set(fiber.HeaderCacheControl, "no-cache")
+	} else {
+		c.Set(fiber.HeaderCacheControl, fmt.Sprintf("private, max-age=%d", int32(UsernameTTL.Seconds())))
+	}
+
+	c.Status(fiber.StatusOK)
+	return c.JSON(response)
+}
+
+// sendUsernamesArray responds with the legacy array of the known usernames in request order, failing as a whole when any batch failed
+func (h *Handler) sendUsernamesArray(c *fiber.Ctx, lookups []usernameLookup) error {
+	//output array, unknown usernames are left out like mojang does
+	usernameBodyArray := []*UsernameResponse{}
+
+	for _, lookup := range lookups {
+		if lookup.username != nil {
+			usernameBodyArray = append(usernameBodyArray, lookup.username)
+			continue
+		}
+
+		for _, err := range lookup.errs {
+			h.Logger.Error("%v", err)
+		}
+
+		if len(lookup.errs) > 0 {
 			return c.SendStatus(fiber.StatusInternalServerError)
 		}
 
-		if exists {
-			//cache hit
-			h.Logger.Info("[%s] Cache Hit for [%s]", textureid, remoteAddr)
+		if lookup.code != fiber.StatusNotFound {
+			return c.SendStatus(lookup.code)
+		}
+	}
 
-			//aggregate cached base64textures
-			base64TextureArray = append(base64TextureArray, item)
-		} else {
-			//cache miss
-			h.Logger.Info("[%s] Cache Miss for %s", textureid, remoteAddr)
+	out, err := json.Marshal(usernameBodyArray)
+	if err != nil {
+		c.Status(fiber.StatusInternalServerError)
+		h.Logger.Error("%v", err)
+		return fiber.NewError(fiber.StatusInternalServerError, "json Marhsal for mojang response failed")
+	}
+
+	c.Status(fiber.StatusOK)
+	c.Set(fiber.HeaderCacheControl, fmt.Sprintf("private, max-age=%d", int32(UsernameTTL.Seconds())))
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	return c.Send(out)
+}
+
+func (h *Handler) GetRender(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	renderType := c.Params("type")
+	rawUUID := c.Params("uuid")
+	remoteAddr := c.Context().Conn().RemoteAddr().String()
+
+	h.Logger.Info("%s GET /render/%s/%s", remoteAddr, renderType, rawUUID)
+
+	renderer, ok := renderers[renderType]
+	if !ok {
+		c.Status(fiber.StatusNotFound)
+		return c.SendString(fmt.Sprintf("bad render type: %s", renderType))
+	}
+
+	playerUUID, err := ParseUUID(rawUUID)
+	if err != nil {
+		c.Status(fiber.StatusBadRequest)
+		return c.SendString(fmt.Sprintf("bad uuid: %s", rawUUID))
+	}
+
+	size, overlay, err := parseRenderQuery(c)
+	if err != nil {
+		c.Status(fiber.StatusBadRequest)
+		return c.SendString(err.Error())
+	}
+
+	//resolve the skin of the player
+	code, textureid, slim, errs := h.lookupSkin(ctx, playerUUID)
+	if textureid == "" {
+		for _, err := range errs {
+			h.Logger.Error("%v", err)
+		}
 
-			//add non-cached uuids to be looked up
-			nonCachedTextureIds = append(nonCachedTextureIds, textureid)
+		if code == fiber.StatusNotFound {
+			return h.sendNotFound(c, "no player with uuid %s", playerUUID)
 		}
+
+		return c.SendStatus(code)
 	}
 
-	if len(nonCachedTextureIds) > 0 {
-		//lookup non-cached uuids
-		responses := h.FetchTextures(nonCachedTextureIds)
+	//check if the render exists in redis already and is not expired
+	key := fmt.Sprintf("render:%s:%s:%d:%t:%t", renderType, textureid, size, overlay, slim)
+	exists, item, err := h.CacheGet(ctx, key)
 
-		//check for errors in multi-fetch
-		for _, response := range responses {
-			if len(response.Errs) > 0 {
-				for _, err := range response.Errs {
-					h.Logger.Error("%v", err)
-				}
+	//check if a redis error occurred
+	if err != nil {
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
 
-				return c.SendStatus(fiber.StatusInternalServerError)
-			}
+	if exists {
+		//cache hit
+		h.Logger.Info("[%s] Cache Hit for [%s]", key, remoteAddr)
+		return sendRender(c, []byte(item))
+	}
 
-			if response.Base64Texture == "" {
-				for _, err := range response.Errs {
-					h.Logger.Error("%v", err)
-				}
+	//cache miss
+	h.Logger.Info("[%s] Cache Miss for %s", key, remoteAddr)
+	skin, code, errs := h.lookupSkinImage(ctx, textureid, slim)
+	if skin == nil {
+		for _, err := range errs {
+			h.Logger.Error("%v", err)
+		}
 
-				return c.SendStatus(fiber.StatusInternalServerError)
-			}
+		if code == fiber.StatusNotFound {
+			return h.sendNotFound(c, "no skin with texture id %s", textureid)
+		}
 
-			if response.Code != fiber.StatusOK {
-				for _, err := range response.Errs {
-					h.Logger.Error("%v", err)
-				}
+		return c.SendStatus(code)
+	}
 
-				return c.SendStatus(response.Code)
-			}
+	out := &bytes.Buffer{}
+	err = png.Encode(out, renderer(skin, size, overlay))
+	if err != nil {
+		h.Logger.Error("%v", err)
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	//cache the render
+	err = h.CachePut(ctx, key, out.String(), RenderTTL)
+	if err != nil {
+		h.Logger.Error("[%s] Failed to cache render: %v", key, err)
+	}
+
+	return sendRender(c, out.Bytes())
+}
+
+func (h *Handler) GetRender3D(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	rawUUID := c.Params("uuid")
+	remoteAddr := c.Context().Conn().RemoteAddr().String()
+
+	h.Logger.Info("%s GET /render/3d/%s", remoteAddr, rawUUID)
+
+	playerUUID, err := ParseUUID(rawUUID)
+	if err != nil {
+		c.Status(fiber.StatusBadRequest)
+		return c.SendString(fmt.Sprintf("bad uuid: %s", rawUUID))
+	}
+
+	size, overlay, err := parseRenderQuery(c)
+	if err != nil {
+		c.Status(fiber.StatusBadRequest)
+		return c.SendString(err.Error())
+	}
+
+	withCape, err := strconv.ParseBool(c.Query("cape", "false"))
+	if err != nil {
+		c.Status(fiber.StatusBadRequest)
+		return c.SendString(fmt.Sprintf("bad cape: %s", c.Query("cape")))
+	}
+
+	view := c.Query("view", "front")
+	if view != "front" && view != "back" {
+		c.Status(fiber.StatusBadRequest)
+		return c.SendString(fmt.Sprintf("bad view: %s", view))
+	}
+
+	//resolve the textures of the player
+	code, textureResponse, errs := h.lookupTextures(ctx, playerUUID)
+	if textureResponse == nil {
+		for _, err := range errs {
+			h.Logger.Error("%v", err)
 		}
 
-		//aggregate new responses and cache them
-		for _, response := range responses {
-			base64TextureArray = append(base64TextureArray, response.Base64Texture)
+		if code == fiber.StatusNotFound {
+			return h.sendNotFound(c, "no player with uuid %s", playerUUID)
+		}
 
-			err := h.CachePut(response.Id, response.Base64Texture, TTL)
-			if err != nil {
-				h.Logger.Error("[%s] Failed to cache profile: %v", response.Id, err)
+		return c.SendStatus(code)
+	}
+
+	textureid, slim := textureResponse.SkinTexture()
+
+	capeid := ""
+	if withCape {
+		capeid = TextureId(textureResponse.Textures.Cape.Url)
+	}
+
+	//check if the render exists in redis already, the key changes whenever the skin or cape does
+	key := fmt.Sprintf("render:3d:%s:%s:%d:%t:%t:%s", textureid, capeid, size, overlay, slim, view)
+	exists, item, err := h.CacheGet(ctx, key)
+
+	//check if a redis error occurred
+	if err != nil {
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	if exists {
+		//cache hit
+		h.Logger.Info("[%s] Cache Hit for [%s]", key, remoteAddr)
+		return sendRender(c, []byte(item))
+	}
+
+	//cache miss
+	h.Logger.Info("[%s] Cache Miss for %s", key, remoteAddr)
+	skin, code, errs := h.lookupSkinImage(ctx, textureid, slim)
+	if skin == nil {
+		for _, err := range errs {
+			h.Logger.Error("%v", err)
+		}
+
+		if code == fiber.StatusNotFound {
+			return h.sendNotFound(c, "no skin with texture id %s", textureid)
+		}
+
+		return c.SendStatus(code)
+	}
+
+	options := render.IsometricOptions{
+		Width:   size,
+		Overlay: overlay,
+		Back:    view == "back",
+	}
+
+	if capeid != "" {
+		options.Cape, code, errs = h.lookupCapeImage(ctx, capeid)
+		if options.Cape == nil {
+			for _, err := range errs {
+				h.Logger.Error("%v", err)
 			}
+
+			if code == fiber.StatusNotFound {
+				return h.sendNotFound(c, "no cape with texture id %s", capeid)
+			}
+
+			return c.SendStatus(code)
 		}
 	}
 
-	out, err := json.Marshal(base64TextureArray)
+	out := &bytes.Buffer{}
+	err = png.Encode(out, render.Isometric(skin, options))
 	if err != nil {
-		c.Status(fiber.StatusInternalServerError)
 		h.Logger.Error("%v", err)
-		return fiber.NewError(fiber.StatusInternalServerError, "json Marhsal for mojang response failed")
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	//cache the render
+	err = h.CachePut(ctx, key, out.String(), RenderTTL)
+	if err != nil {
+		h.Logger.Error("[%s] Failed to cache render: %v", key, err)
 	}
 
+	return sendRender(c, out.Bytes())
+}
+
+// sendRender responds with a rendered png, only successful renders are labelled as images and cached by clients
+func sendRender(c *fiber.Ctx, body []byte) error {
+	c.Status(fiber.StatusOK)
+	c.Set(fiber.HeaderContentType, "image/png")
 	c.Set(fiber.HeaderCacheControl, fmt.Sprintf("private, max-age=%d", int32(TTL.Seconds())))
+	return c.Send(body)
+}
+
+func (h *Handler) GetServer(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	address := strings.ToLower(c.Params("host"))
+	remoteAddr := c.Context().Conn().RemoteAddr().String()
+
+	h.Logger.Info("%s GET /server/%s", remoteAddr, address)
+
+	if !isValidServerAddress(address) {
+		c.Status(fiber.StatusBadRequest)
+		return c.SendString(fmt.Sprintf("bad host: %s", address))
+	}
+
+	format := c.Query("format", "plain")
+	if _, err := motd.Format(nil, format); err != nil {
+		c.Status(fiber.StatusBadRequest)
+		return c.SendString(fmt.Sprintf("bad format: %s", format))
+	}
+
+	response, code, err := h.lookupServer(ctx, address, remoteAddr)
+	if response == nil {
+		h.Logger.Error("[%s] Failed to ping server: %v", address, err)
+		c.Status(code)
+		return c.SendString(fmt.Sprintf("failed to ping server: %s", address))
+	}
+
+	//render the motd in the requested format
+	segments, err := motd.Parse(response.Description)
+	if err == nil {
+		response.MOTD, _ = motd.Format(segments, format)
+	}
+
 	c.Status(fiber.StatusOK)
-	return c.Send(out)
+	c.Set(fiber.HeaderCacheControl, fmt.Sprintf("public, max-age=%d", int32(ServerTTL.Seconds())))
+	return c.JSON(response)
+}
+
+func (h *Handler) GetServerIcon(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	address := strings.ToLower(c.Params("host"))
+	remoteAddr := c.Context().Conn().RemoteAddr().String()
+
+	h.Logger.Info("%s GET /server/%s/icon.png", remoteAddr, address)
+
+	if !isValidServerAddress(address) {
+		c.Status(fiber.StatusBadRequest)
+		return c.SendString(fmt.Sprintf("bad host: %s", address))
+	}
+
+	response, code, err := h.lookupServer(ctx, address, remoteAddr)
+	if response == nil {
+		h.Logger.Error("[%s] Failed to ping server: %v", address, err)
+		c.Status(code)
+		return c.SendString(fmt.Sprintf("failed to ping server: %s", address))
+	}
+
+	//the favicon is sent as a data uri
+	if !strings.HasPrefix(response.Favicon, "data:image/png;base64,") {
+		c.Status(fiber.StatusNotFound)
+		return c.SendString(fmt.Sprintf("server has no icon: %s", address))
+	}
+
+	icon, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(response.Favicon, "data:image/png;base64,"))
+	if err != nil {
+		h.Logger.Error("[%s] Failed to decode server icon: %v", address, err)
+		return c.SendStatus(fiber.StatusBadGateway)
+	}
+
+	c.Status(fiber.StatusOK)
+	c.Set(fiber.HeaderContentType, "image/png")
+	c.Set(fiber.HeaderCacheControl, fmt.Sprintf("public, max-age=%d", int32(ServerTTL.Seconds())))
+	return c.Send(icon)
+}
+
+func (h *Handler) GetBedrockServer(c *fiber.Ctx) error {
+	ctx := c.UserContext()
+	address := strings.ToLower(c.Params("host"))
+	remoteAddr := c.Context().Conn().RemoteAddr().String()
+
+	h.Logger.Info("%s GET /server/bedrock/%s", remoteAddr, address)
+
+	if !isValidServerAddress(address) {
+		c.Status(fiber.StatusBadRequest)
+		return c.SendString(fmt.Sprintf("bad host: %s", address))
+	}
+
+	format := c.Query("format", "plain")
+	if _, err := motd.Format(nil, format); err != nil {
+		c.Status(fiber.StatusBadRequest)
+		return c.SendString(fmt.Sprintf("bad format: %s", format))
+	}
+
+	response, code, err := h.lookupBedrockServer(ctx, address, remoteAddr)
+	if response == nil {
+		h.Logger.Error("[%s] Failed to ping bedrock server: %v", address, err)
+		c.Status(code)
+		return c.SendString(fmt.Sprintf("failed to ping server: %s", address))
+	}
+
+	//render the motd lines in the requested format
+	response.MOTD = response.MOTD[:0]
+	for _, line := range response.RawMOTD {
+		formatted, _ := motd.Format(motd.ParseLegacy(line), format)
+		response.MOTD = append(response.MOTD, formatted)
+	}
+
+	c.Status(fiber.StatusOK)
+	c.Set(fiber.HeaderCacheControl, fmt.Sprintf("public, max-age=%d", int32(ServerTTL.Seconds())))
+	return c.JSON(response)
 }
 
 func (h *Handler) GetSearchKey(c *fiber.Ctx) error {
-	keys, err := h.MSClient.GetKeys(&meilisearch.KeysQuery{
-		Offset: 0,
-		Limit:  2,
-	})
+	keys, err := h.searchKeys(c.UserContext())
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		h.Logger.Error("Key Error: %v", err)
+		return c.SendStatus(fiber.StatusGatewayTimeout)
+	}
 
 	if err != nil {
 		h.Logger.Error("Key Error: %v", err)
@@ -400,3 +1059,47 @@ func (h *Handler) GetSearchKey(c *fiber.Ctx) error {
 		return c.SendStatus(fiber.StatusInternalServerError)
 	}
 }
+
+// searchKeys lists the meilisearch api keys, giving up once ctx is done. The meilisearch client takes no context,
+// so its own timeout ends a call that is given up on
+func (h *Handler) searchKeys(ctx context.Context) (*meilisearch.KeysResults, error) {
+	type outcome struct {
+		keys *meilisearch.KeysResults
+		err  error
+	}
+
+	done := make(chan outcome, 1)
+
+	go func() {
+		keys, err := h.MSClient.GetKeys(&meilisearch.KeysQuery{
+			Offset: 0,
+			Limit:  2,
+		})
+
+		done <- outcome{keys, err}
+	}()
+
+	select {
+	case o := <-done:
+		return o.keys, o.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// GetIPPool shows the request budget and cooldown of every source ip, guarded by the AdminKey
+func (h *Handler) GetIPPool(c *fiber.Ctx) error {
+	remoteAddr := c.Context().Conn().RemoteAddr().String()
+	apiKey := c.Get("x-bedgg-api-key")
+
+	if h.AdminKey == "" || apiKey != h.AdminKey {
+		h.Logger.Error("Incorrect API Key provided from %s", remoteAddr)
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+
+	if h.Scheduler == nil {
+		return c.JSON([]IPState{})
+	}
+
+	return c.JSON(h.Scheduler.State())
+}
@@ -0,0 +1,737 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"bed.gg/minecraft-api/v2/src/logger"
+	"bed.gg/minecraft-api/v2/src/mojangtest"
+	"bed.gg/minecraft-api/v2/src/serverping"
+	"github.com/gofiber/fiber/v2"
+)
+
+// newTestApp serves the routes of a handler backed by a MemoryCache and the fake mojang api
+func newTestApp(t *testing.T) (*fiber.App, *mojangtest.Server) {
+	handler, server := newFakeHandler(t)
+	handler.Cache = NewMemoryCache()
+	handler.Ctx = context.Background()
+
+	app := fiber.New()
+	app.Get("/profile/:uuid", handler.GetProfile)
+	app.Get("/profile/name/:username", handler.GetUUID)
+	app.Get("/profile/:uuid/decoded", handler.GetDecodedProfile)
+	app.Get("/profiles", handler.GetProfiles)
+	app.Get("/texture/:textureid.png", handler.GetTexturePNG)
+	app.Get("/texture/:textureid", handler.GetTexture)
+	app.Get("/textures", handler.GetTextures)
+	app.Get("/uuid/:username", handler.GetUUID)
+	app.Get("/render/3d/:uuid", handler.GetRender3D)
+	app.Get("/render/:type/:uuid", handler.GetRender)
+
+	return app, server
+}
+
+func doRequest(t *testing.T, app *fiber.App, req *http.Request) (int, []byte) {
+	res, err := app.Test(req, -1)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := io.ReadAll(res.Body)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return res.StatusCode, body
+}
+
+func jsonRequest(path string, body string) *http.Request {
+	req := httptest.NewRequest(fiber.MethodGet, path, strings.NewReader(body))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+
+	return req
+}
+
+func TestGetProfile(t *testing.T) {
+	app, server := newTestApp(t)
+
+	//dashed and uppercase variants share the cache entry of the canonical uuid
+	for _, id := range []string{notch.Id, "069A79F4-44E9-4726-A5BE-FCA90E38AAF5"} {
+		code, body := doRequest(t, app, httptest.NewRequest(fiber.MethodGet, "/profile/"+id, nil))
+
+		if code != fiber.StatusOK {
+			t.Fatalf("GET /profile/%s = %d %s", id, code, body)
+		}
+
+		profile := &ProfileResponse{}
+		if err := json.Unmarshal(body, profile); err != nil {
+			t.Fatal(err)
+		}
+
+		if profile.Name != notch.Name || !profile.Verified {
+			t.Errorf("GET /profile/%s = %+v", id, profile)
+		}
+	}
+
+	if server.Requests() != 1 {
+		t.Errorf("fake api served %d requests, want 1", server.Requests())
+	}
+
+	code, _ := doRequest(t, app, httptest.NewRequest(fiber.MethodGet, "/profile/notauuid", nil))
+	if code != fiber.StatusBadRequest {
+		t.Errorf("GET /profile/notauuid = %d, want 400", code)
+	}
+}
+
+func TestGetDecodedProfile(t *testing.T) {
+	app, server := newTestApp(t)
+
+	expected := map[string]*DecodedProfile{
+		notch.Id: {Id: notch.Id, Name: notch.Name, SkinTextureId: server.SkinTextureId(notch.Id), Model: "classic", CapeTextureId: server.CapeTextureId(notch.Id)},
+		jeb.Id:   {Id: jeb.Id, Name: jeb.Name, SkinTextureId: server.SkinTextureId(jeb.Id), Model: "slim"},
+		alex.Id:  {Id: alex.Id, Name: alex.Name, SkinTextureId: AlexTextureId, Model: "slim"},
+	}
+
+	for id, want := range expected {
+		code, body := doRequest(t, app, httptest.NewRequest(fiber.MethodGet, "/profile/"+id+"/decoded", nil))
+
+		profile := &DecodedProfile{}
+		if err := json.Unmarshal(body, profile); err != nil || code != fiber.StatusOK {
+			t.Fatalf("GET /profile/%s/decoded = %d %s", id, code, body)
+		}
+
+		if profile.Id != want.Id || profile.Name != want.Name || profile.SkinTextureId != want.SkinTextureId ||
+			profile.Model != want.Model || profile.CapeTextureId != want.CapeTextureId {
+			t.Errorf("GET /profile/%s/decoded = %+v, want %+v", id, profile, want)
+		}
+
+		//the urls point at the texture ids and the signature was checked
+		if (want.CapeTextureId != "") != strings.HasSuffix(profile.CapeUrl, "/"+want.CapeTextureId) || !profile.Verified || profile.Timestamp == 0 {
+			t.Errorf("GET /profile/%s/decoded = %+v", id, profile)
+		}
+	}
+
+	assertNotFound(t, app, "/profile/00000000000040008000000000000000/decoded")
+
+	code, _ := doRequest(t, app, httptest.NewRequest(fiber.MethodGet, "/profile/notauuid/decoded", nil))
+	if code != fiber.StatusBadRequest {
+		t.Errorf("GET /profile/notauuid/decoded = %d, want 400", code)
+	}
+}
+
+func TestGetUUID(t *testing.T) {
+	app, server := newTestApp(t)
+
+	//names are case-insensitive and share a cache entry across both routes
+	for _, path := range []string{"/uuid/Notch", "/uuid/notch", "/profile/name/NOTCH"} {
+		code, body := doRequest(t, app, httptest.NewRequest(fiber.MethodGet, path, nil))
+
+		username := &UsernameResponse{}
+		if err := json.Unmarshal(body, username); err != nil || code != fiber.StatusOK {
+			t.Fatalf("GET %s = %d %s", path, code, body)
+		}
+
+		if username.Id != notch.Id || username.Name != notch.Name {
+			t.Errorf("GET %s = %+v", path, username)
+		}
+	}
+
+	if server.Requests() != 1 {
+		t.Errorf("fake api served %d requests, want 1", server.Requests())
+	}
+
+	//unknown names are remembered for the NegativeTTL
+	for i := 0; i < 2; i++ {
+		assertNotFound(t, app, "/uuid/nobody_here")
+	}
+
+	if server.Requests() != 2 {
+		t.Errorf("fake api served %d requests after an unknown name twice, want 2", server.Requests())
+	}
+
+	code, _ := doRequest(t, app, httptest.NewRequest(fiber.MethodGet, "/uuid/not-a-name", nil))
+	if code != fiber.StatusBadRequest {
+		t.Errorf("GET /uuid/not-a-name = %d, want 400", code)
+	}
+}
+
+func TestGetProfiles(t *testing.T) {
+	app, _ := newTestApp(t)
+
+	missing := "00000000000040008000000000000000"
+	ids := []string{jeb.Id, missing, notch.Id, alex.Id}
+
+	code, body := doRequest(t, app, jsonRequest("/profiles", `{"uuids":["`+strings.Join(ids, `","`)+`"]}`))
+
+	if code != fiber.StatusOK {
+		t.Fatalf("GET /profiles = %d %s", code, body)
+	}
+
+	//a missing player is reported on its own, next to the players that were found
+	response := &struct {
+		Results map[string]*ProfileResponse `json:"results"`
+		Errors  map[string]*BatchError      `json:"errors"`
+	}{}
+
+	if err := json.Unmarshal(body, response); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, player := range []mojangtest.Player{notch, jeb, alex} {
+		if profile := response.Results[player.Id]; profile == nil || profile.Name != player.Name {
+			t.Errorf("GET /profiles result for %s = %+v", player.Name, profile)
+		}
+	}
+
+	if err := response.Errors[missing]; err == nil || err.Code != fiber.StatusNotFound || len(response.Errors) != 1 {
+		t.Errorf("GET /profiles errors = %+v, want a 404 for %s", response.Errors, missing)
+	}
+
+	assertRequestOrder(t, body, []string{jeb.Id, notch.Id, alex.Id})
+
+	//the legacy array keeps request order and fails as a whole
+	code, body = doRequest(t, app, jsonRequest("/profiles?legacy=true", `{"uuids":["`+jeb.Id+`","`+notch.Id+`"]}`))
+
+	var profiles []*ProfileResponse
+	if err := json.Unmarshal(body, &profiles); err != nil || code != fiber.StatusOK {
+		t.Fatalf("GET /profiles?legacy=true = %d %s", code, body)
+	}
+
+	if len(profiles) != 2 || profiles[0].Name != jeb.Name || profiles[1].Name != notch.Name {
+		t.Errorf("GET /profiles?legacy=true = %s, want jeb then notch", body)
+	}
+
+	code, _ = doRequest(t, app, jsonRequest("/profiles?legacy=true", `{"uuids":["`+strings.Join(ids, `","`)+`"]}`))
+	if code != fiber.StatusNotFound {
+		t.Errorf("GET /profiles?legacy=true with a missing player = %d, want 404", code)
+	}
+
+	code, _ = doRequest(t, app, jsonRequest("/profiles", `{"uuids":["notauuid"]}`))
+	if code != fiber.StatusBadRequest {
+		t.Errorf("GET /profiles with a bad uuid = %d, want 400", code)
+	}
+}
+
+func TestPostUUIDs(t *testing.T) {
+	handler, server := newFakeHandler(t)
+	handler.Cache = NewMemoryCache()
+	handler.Mojang.(*FasthttpMojangClient).Retry = NoRetryPolicy
+
+	app := fiber.New()
+	app.Post("/uuids", handler.PostUUIDs)
+
+	postUUIDs := func(path string, names []string) (int, []byte) {
+		req := jsonRequest(path, `{"usernames":["`+strings.Join(names, `","`)+`"]}`)
+		req.Method = fiber.MethodPost
+
+		return doRequest(t, app, req)
+	}
+
+	var players []string
+	for i := 0; i < 12; i++ {
+		player := mojangtest.Player{Id: fmt.Sprintf("%032x", 0x1000+i), Name: fmt.Sprintf("player%02d", i)}
+		server.AddPlayer(player)
+		players = append(players, player.Name)
+	}
+
+	//cache a known and an unknown name
+	code, body := postUUIDs("/uuids", []string{"jeb_", "nobody_here"})
+	if code != fiber.StatusOK {
+		t.Fatalf("POST /uuids = %d %s", code, body)
+	}
+
+	//15 names, 2 of them cached, resolve in a full and a partial batch of uncached names
+	names := append([]string{"player11", "JEB_", "nobody_here"}, players[:11]...)
+	names = append(names, "Notch")
+
+	requests := server.Requests()
+	code, body = postUUIDs("/uuids", names)
+
+	if code != fiber.StatusOK {
+		t.Fatalf("POST /uuids = %d %s", code, body)
+	}
+
+	if server.Requests()-requests != 2 {
+		t.Errorf("POST /uuids made %d upstream requests, want 2 batches", server.Requests()-requests)
+	}
+
+	response := &struct {
+		Results map[string]*UsernameResponse `json:"results"`
+		Errors  map[string]*BatchError       `json:"errors"`
+	}{}
+
+	if err := json.Unmarshal(body, response); err != nil {
+		t.Fatal(err)
+	}
+
+	//results are keyed by the requested name, in request order
+	if username := response.Results["JEB_"]; username == nil || username.Id != jeb.Id {
+		t.Errorf("POST /uuids result for JEB_ = %+v", username)
+	}
+
+	if err := response.Errors["nobody_here"]; err == nil || err.Code != fiber.StatusNotFound || len(response.Errors) != 1 {
+		t.Errorf("POST /uuids errors = %+v, want a 404 for nobody_here", response.Errors)
+	}
+
+	if len(response.Results) != len(names)-1 {
+		t.Errorf("POST /uuids resolved %d names, want %d", len(response.Results), len(names)-1)
+	}
+
+	found := append([]string{"player11", "JEB_"}, players[:11]...)
+	assertRequestOrder(t, body, append(found, "Notch"))
+
+	//the legacy array keeps request order and leaves unknown names out
+	code, body = postUUIDs("/uuids?legacy=true", []string{"Notch", "nobody_here", "jeb_"})
+
+	var usernames []*UsernameResponse
+	if err := json.Unmarshal(body, &usernames); err != nil || code != fiber.StatusOK {
+		t.Fatalf("POST /uuids?legacy=true = %d %s", code, body)
+	}
+
+	if len(usernames) != 2 || usernames[0].Id != notch.Id || usernames[1].Id != jeb.Id {
+		t.Errorf("POST /uuids?legacy=true = %s, want notch then jeb", body)
+	}
+
+	//a failed batch fails only its own names
+	handler.Cache = NewMemoryCache()
+	server.Fail(1, fiber.StatusServiceUnavailable)
+
+	code, body = postUUIDs("/uuids", players)
+	response.Results, response.Errors = nil, nil
+
+	if err := json.Unmarshal(body, response); err != nil || code != fiber.StatusOK {
+		t.Fatalf("POST /uuids during an outage = %d %s", code, body)
+	}
+
+	if len(response.Errors) != 10 && len(response.Errors) != 2 {
+		t.Errorf("POST /uuids during an outage failed %d names, want one batch", len(response.Errors))
+	}
+
+	if len(response.Results)+len(response.Errors) != len(players) {
+		t.Errorf("POST /uuids during an outage reported %d results and %d errors, want %d names", len(response.Results), len(response.Errors), len(players))
+	}
+
+	for name, err := range response.Errors {
+		if err.Code != fiber.StatusServiceUnavailable {
+			t.Errorf("POST /uuids error for %s = %+v, want 503", name, err)
+		}
+	}
+
+	code, _ = postUUIDs("/uuids", []string{"not a name"})
+	if code != fiber.StatusBadRequest {
+		t.Errorf("POST /uuids with a bad username = %d, want 400", code)
+	}
+}
+
+// assertRequestOrder checks the ids appear in body in the given order
+func assertRequestOrder(t *testing.T, body []byte, ids []string) {
+	last := -1
+
+	for _, id := range ids {
+		idx := strings.Index(string(body), `"`+id+`"`)
+
+		if idx <= last {
+			t.Errorf("%s is out of request order in %s", id, body)
+		}
+
+		last = idx
+	}
+}
+
+func TestGetTexture(t *testing.T) {
+	app, server := newTestApp(t)
+
+	textureid := server.SkinTextureId(jeb.Id)
+	texture, _ := server.Texture(textureid)
+
+	code, body := doRequest(t, app, httptest.NewRequest(fiber.MethodGet, "/texture/"+textureid, nil))
+
+	if code != fiber.StatusOK || string(body) != base64.StdEncoding.EncodeToString(texture) {
+		t.Errorf("GET /texture/%s = %d", textureid, code)
+	}
+
+	req := httptest.NewRequest(fiber.MethodGet, "/texture/"+textureid, nil)
+	req.Header.Set(fiber.HeaderAccept, "image/png")
+	code, body = doRequest(t, app, req)
+
+	if code != fiber.StatusOK || string(body) != string(texture) {
+		t.Errorf("GET /texture/%s as png = %d", textureid, code)
+	}
+
+	code, _ = doRequest(t, app, httptest.NewRequest(fiber.MethodGet, "/texture/notahexid", nil))
+	if code != fiber.StatusBadRequest {
+		t.Errorf("GET /texture/notahexid = %d, want 400", code)
+	}
+}
+
+func TestGetTexturePNG(t *testing.T) {
+	app, server := newTestApp(t)
+
+	textureid := server.SkinTextureId(notch.Id)
+	texture, _ := server.Texture(textureid)
+	etag := `"` + textureid + `"`
+
+	res, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/texture/"+textureid+".png", nil), -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, _ := io.ReadAll(res.Body)
+	if res.StatusCode != fiber.StatusOK || string(body) != string(texture) || res.Header.Get(fiber.HeaderETag) != etag {
+		t.Fatalf("GET /texture/%s.png = %d etag %s", textureid, res.StatusCode, res.Header.Get(fiber.HeaderETag))
+	}
+
+	requests := server.Requests()
+
+	//a client holding the texture is answered without a body or an upstream request
+	for _, ifNoneMatch := range []string{etag, `W/` + etag, `"other", ` + etag, `"other",W/` + etag + ` , "another"`, "*"} {
+		req := httptest.NewRequest(fiber.MethodGet, "/texture/"+textureid+".png", nil)
+		req.Header.Set(fiber.HeaderIfNoneMatch, ifNoneMatch)
+		code, body := doRequest(t, app, req)
+
+		if code != fiber.StatusNotModified || len(body) != 0 {
+			t.Errorf("GET /texture/%s.png If-None-Match %s = %d, want 304", textureid, ifNoneMatch, code)
+		}
+	}
+
+	if server.Requests() != requests {
+		t.Errorf("304s made %d upstream requests, want none", server.Requests()-requests)
+	}
+
+	for _, ifNoneMatch := range []string{`"other"`, `"other", W/"another"`, textureid} {
+		req := httptest.NewRequest(fiber.MethodGet, "/texture/"+textureid+".png", nil)
+		req.Header.Set(fiber.HeaderIfNoneMatch, ifNoneMatch)
+		code, body := doRequest(t, app, req)
+
+		if code != fiber.StatusOK || string(body) != string(texture) {
+			t.Errorf("GET /texture/%s.png If-None-Match %s = %d, want the texture", textureid, ifNoneMatch, code)
+		}
+	}
+
+	//missing textures carry no etag
+	res, err = app.Test(httptest.NewRequest(fiber.MethodGet, "/texture/deadbeef.png", nil), -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.StatusCode != fiber.StatusNotFound || res.Header.Get(fiber.HeaderETag) != "" {
+		t.Errorf("GET /texture/deadbeef.png = %d etag %s, want a 404 without etag", res.StatusCode, res.Header.Get(fiber.HeaderETag))
+	}
+}
+
+func TestGetTextures(t *testing.T) {
+	app, server := newTestApp(t)
+
+	skin := server.SkinTextureId(notch.Id)
+	cape := server.CapeTextureId(notch.Id)
+	missing := "deadbeef"
+
+	code, body := doRequest(t, app, jsonRequest("/textures", `{"textures":["`+cape+`","`+missing+`","`+skin+`"]}`))
+
+	if code != fiber.StatusOK {
+		t.Fatalf("GET /textures = %d %s", code, body)
+	}
+
+	response := &struct {
+		Results map[string]string      `json:"results"`
+		Errors  map[string]*BatchError `json:"errors"`
+	}{}
+
+	if err := json.Unmarshal(body, response); err != nil {
+		t.Fatal(err)
+	}
+
+	//every base64 texture is keyed by the id it belongs to
+	for _, textureid := range []string{skin, cape} {
+		texture, _ := server.Texture(textureid)
+
+		if response.Results[textureid] != base64.StdEncoding.EncodeToString(texture) {
+			t.Errorf("GET /textures result for %s does not match the texture", textureid)
+		}
+	}
+
+	if err := response.Errors[missing]; err == nil || err.Code != fiber.StatusNotFound {
+		t.Errorf("GET /textures errors = %+v, want a 404 for %s", response.Errors, missing)
+	}
+
+	assertRequestOrder(t, body, []string{cape, skin})
+
+	//the legacy array keeps request order
+	code, body = doRequest(t, app, jsonRequest("/textures?legacy=true", `{"textures":["`+cape+`","`+skin+`"]}`))
+
+	var textures []string
+	if err := json.Unmarshal(body, &textures); err != nil || code != fiber.StatusOK {
+		t.Fatalf("GET /textures?legacy=true = %d %s", code, body)
+	}
+
+	if len(textures) != 2 || textures[0] != response.Results[cape] || textures[1] != response.Results[skin] {
+		t.Errorf("GET /textures?legacy=true is not in request order")
+	}
+}
+
+func TestGetTexturesDuplicates(t *testing.T) {
+	app, server := newTestApp(t)
+
+	skin := server.SkinTextureId(notch.Id)
+	cape := server.CapeTextureId(notch.Id)
+
+	//a repeated id, in any case, is fetched and reported once
+	code, body := doRequest(t, app, jsonRequest("/textures", `{"textures":["`+skin+`","`+cape+`","`+strings.ToUpper(skin)+`","`+skin+`"]}`))
+
+	if code != fiber.StatusOK {
+		t.Fatalf("GET /textures = %d %s", code, body)
+	}
+
+	response := &struct {
+		Results map[string]string      `json:"results"`
+		Errors  map[string]*BatchError `json:"errors"`
+	}{}
+
+	if err := json.Unmarshal(body, response); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(response.Results) != 2 || len(response.Errors) != 0 {
+		t.Errorf("GET /textures = %s, want one result per texture", body)
+	}
+
+	if requests := server.Requests(); requests != 2 {
+		t.Errorf("GET /textures made %d requests, want 2", requests)
+	}
+
+	//the legacy array holds every texture once too
+	code, body = doRequest(t, app, jsonRequest("/textures?legacy=true", `{"textures":["`+skin+`","`+skin+`"]}`))
+
+	var textures []string
+	if err := json.Unmarshal(body, &textures); err != nil || code != fiber.StatusOK {
+		t.Fatalf("GET /textures?legacy=true = %d %s", code, body)
+	}
+
+	if len(textures) != 1 || textures[0] != response.Results[skin] {
+		t.Errorf("GET /textures?legacy=true = %s, want the texture once", body)
+	}
+}
+
+// assertNotFound checks a response is the json 404 of a missing player or texture, not an image
+func assertNotFound(t *testing.T, app *fiber.App, path string) {
+	res, err := app.Test(httptest.NewRequest(fiber.MethodGet, path, nil), -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := &ErrorResponse{}
+	if err := json.NewDecoder(res.Body).Decode(body); err != nil || res.StatusCode != fiber.StatusNotFound || body.Error != "NotFound" {
+		t.Errorf("GET %s = %d %+v %v, want a 404 error body", path, res.StatusCode, body, err)
+	}
+
+	if contentType := res.Header.Get(fiber.HeaderContentType); contentType != fiber.MIMEApplicationJSON {
+		t.Errorf("GET %s Content-Type = %s, want json", path, contentType)
+	}
+}
+
+func TestGetRender(t *testing.T) {
+	app, _ := newTestApp(t)
+
+	for _, renderType := range []string{"face", "head", "bust", "body"} {
+		res, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/render/"+renderType+"/"+notch.Id+"?size=64", nil), -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		img, err := png.Decode(res.Body)
+		if err != nil || res.StatusCode != fiber.StatusOK || res.Header.Get(fiber.HeaderContentType) != "image/png" {
+			t.Fatalf("GET /render/%s = %d %s %v", renderType, res.StatusCode, res.Header.Get(fiber.HeaderContentType), err)
+		}
+
+		if img.Bounds().Dx() != 64 {
+			t.Errorf("GET /render/%s width = %d, want 64", renderType, img.Bounds().Dx())
+		}
+	}
+
+	//unknown players are not labelled as images
+	assertNotFound(t, app, "/render/head/00000000000040008000000000000000")
+}
+
+func TestGetRender3D(t *testing.T) {
+	app, _ := newTestApp(t)
+
+	for _, path := range []string{"/render/3d/" + notch.Id, "/render/3d/" + notch.Id + "?cape=true&view=back"} {
+		res, err := app.Test(httptest.NewRequest(fiber.MethodGet, path, nil), -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := png.Decode(res.Body); err != nil || res.StatusCode != fiber.StatusOK || res.Header.Get(fiber.HeaderContentType) != "image/png" {
+			t.Errorf("GET %s = %d %s %v", path, res.StatusCode, res.Header.Get(fiber.HeaderContentType), err)
+		}
+	}
+
+	//unknown players and skins the textures server does not have are not labelled as images
+	assertNotFound(t, app, "/render/3d/00000000000040008000000000000000")
+	assertNotFound(t, app, "/render/3d/"+alex.Id)
+}
+
+// newServerApp serves the java server routes of a handler backed by a MemoryCache
+func newServerApp(allowPrivate bool) *fiber.App {
+	handler := &Handler{
+		Logger:              logger.NewLogger(),
+		Ctx:                 context.Background(),
+		Cache:               NewMemoryCache(),
+		AllowPrivateServers: allowPrivate,
+	}
+
+	app := fiber.New()
+	app.Get("/server/:host/icon.png", handler.GetServerIcon)
+	app.Get("/server/:host", handler.GetServer)
+
+	return app
+}
+
+// fakeJavaServer answers server list pings on a local port with status, the protocol frames every packet
+// with its length as a varint
+func fakeJavaServer(t *testing.T, status string) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		_ = listener.Close()
+	})
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func(conn net.Conn) {
+				defer conn.Close()
+				_ = conn.SetDeadline(time.Now().Add(2 * time.Second))
+				r := bufio.NewReader(conn)
+
+				//skip the handshake and the status request
+				for i := 0; i < 2; i++ {
+					n, err := binary.ReadUvarint(r)
+					if err != nil {
+						return
+					}
+
+					if _, err := io.CopyN(io.Discard, r, int64(n)); err != nil {
+						return
+					}
+				}
+
+				//the status response, packet id 0 followed by the status json as a string
+				packet := binary.AppendUvarint([]byte{0x00}, uint64(len(status)))
+				packet = append(packet, status...)
+
+				_, _ = conn.Write(append(binary.AppendUvarint(nil, uint64(len(packet))), packet...))
+			}(conn)
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+func TestGetServer(t *testing.T) {
+	status := `{"version":{"name":"1.19.2","protocol":760},"players":{"max":20,"online":1},"description":{"text":"A §aServer"}}`
+	address := fakeJavaServer(t, status)
+	app := newServerApp(true)
+
+	code, body := doRequest(t, app, httptest.NewRequest(fiber.MethodGet, "/server/"+address+"?format=plain", nil))
+
+	if code != fiber.StatusOK {
+		t.Fatalf("GET /server/%s = %d %s", address, code, body)
+	}
+
+	response := &serverping.Response{}
+	if err := json.Unmarshal(body, response); err != nil {
+		t.Fatal(err)
+	}
+
+	if response.Version != "1.19.2" || response.Online != 1 || response.Max != 20 || response.MOTD != "A Server" {
+		t.Errorf("GET /server/%s = %+v", address, response)
+	}
+
+	//a server without a favicon has no icon
+	code, body = doRequest(t, app, httptest.NewRequest(fiber.MethodGet, "/server/"+address+"/icon.png", nil))
+
+	if code != fiber.StatusNotFound {
+		t.Errorf("GET /server/%s/icon.png = %d %s, want 404", address, code, body)
+	}
+
+	for _, path := range []string{"/server/bad_host", "/server/bad_host/icon.png", "/server/" + address + "?format=xml"} {
+		if code, body := doRequest(t, app, httptest.NewRequest(fiber.MethodGet, path, nil)); code != fiber.StatusBadRequest {
+			t.Errorf("GET %s = %d %s, want 400", path, code, body)
+		}
+	}
+}
+
+func TestGetServerUnreachable(t *testing.T) {
+	//a port nothing listens on anymore
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	address := listener.Addr().String()
+	_ = listener.Close()
+
+	app := newServerApp(true)
+
+	for _, path := range []string{"/server/" + address, "/server/" + address + "/icon.png"} {
+		if code, body := doRequest(t, app, httptest.NewRequest(fiber.MethodGet, path, nil)); code != fiber.StatusBadGateway {
+			t.Errorf("GET %s = %d %s, want 502", path, code, body)
+		}
+	}
+
+	//servers on our own network are refused unless allowed, even when they answer
+	address = fakeJavaServer(t, `{"version":{"name":"1.19.2","protocol":760},"players":{"max":20,"online":1},"description":""}`)
+
+	for _, path := range []string{"/server/" + address, "/server/localhost/icon.png"} {
+		code, body := doRequest(t, newServerApp(false), httptest.NewRequest(fiber.MethodGet, path, nil))
+
+		if code != fiber.StatusBadGateway {
+			t.Errorf("GET %s of a private address = %d %s, want 502", path, code, body)
+		}
+	}
+}
+
+func TestGetServerIcon(t *testing.T) {
+	icon := &bytes.Buffer{}
+	if err := png.Encode(icon, image.NewNRGBA(image.Rect(0, 0, 64, 64))); err != nil {
+		t.Fatal(err)
+	}
+
+	favicon := "data:image/png;base64," + base64.StdEncoding.EncodeToString(icon.Bytes())
+	address := fakeJavaServer(t, `{"version":{"name":"1.19.2","protocol":760},"players":{"max":20,"online":1},"description":"","favicon":"`+favicon+`"}`)
+
+	res, err := newServerApp(true).Test(httptest.NewRequest(fiber.MethodGet, "/server/"+address+"/icon.png", nil), -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, _ := io.ReadAll(res.Body)
+
+	if res.StatusCode != fiber.StatusOK || res.Header.Get(fiber.HeaderContentType) != "image/png" || !bytes.Equal(body, icon.Bytes()) {
+		t.Errorf("GET /server/%s/icon.png = %d %s, want the favicon", address, res.StatusCode, res.Header.Get(fiber.HeaderContentType))
+	}
+}
@@ -0,0 +1,182 @@
+package api
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultIPBudget requests a single ip may send per DefaultIPWindow, mojang allows about 600 per 10 minutes
+	DefaultIPBudget = 600
+	// DefaultIPWindow the window the request budget of an ip is counted over
+	DefaultIPWindow = 10 * time.Minute
+	// DefaultIPCooldown how long an ip is skipped after a 429 that came without a Retry-After
+	DefaultIPCooldown = 30 * time.Second
+)
+
+// IPScheduler hands out the source ips of the IPPool, skipping ips that used up their request budget or are cooling down from a 429
+type IPScheduler struct {
+	// Budget requests per Window allowed per ip, 0 disables the budget
+	Budget int
+	// Window the duration the Budget is counted over
+	Window time.Duration
+	// Cooldown how long an ip is skipped after a 429 without a Retry-After
+	Cooldown time.Duration
+
+	mu   sync.Mutex
+	ips  []*ipState
+	next int
+	now  func() time.Time
+}
+
+type ipState struct {
+	ip            net.IP
+	windowStart   time.Time
+	used          int
+	cooldownUntil time.Time
+	requests      uint64
+	rateLimited   uint64
+}
+
+// IPState the state of a single ip of the pool, as shown by the admin endpoint
+type IPState struct {
+	Ip            string    `json:"ip"`
+	Used          int       `json:"used"`
+	Budget        int       `json:"budget"`
+	WindowReset   time.Time `json:"windowReset"`
+	CoolingDown   bool      `json:"coolingDown"`
+	CooldownUntil time.Time `json:"cooldownUntil,omitempty"`
+	Requests      uint64    `json:"requests"`
+	RateLimited   uint64    `json:"rateLimited"`
+}
+
+// NewIPScheduler creates an IPScheduler over the pool with the default budget, window and cooldown
+func NewIPScheduler(pool []net.IP) *IPScheduler {
+	s := &IPScheduler{
+		Budget:   DefaultIPBudget,
+		Window:   DefaultIPWindow,
+		Cooldown: DefaultIPCooldown,
+		now:      time.Now,
+	}
+
+	for _, ip := range pool {
+		s.ips = append(s.ips, &ipState{ip: ip})
+	}
+
+	return s
+}
+
+// Next returns the next available ip in round robin order and counts a request against its budget.
+// When every ip is unavailable the one that becomes available first is used anyway, nil is only returned for an empty pool
+func (s *IPScheduler) Next() net.IP {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.ips) == 0 {
+		return nil
+	}
+
+	now := s.now()
+	var fallback *ipState
+	var fallbackAt time.Time
+
+	for i := 0; i < len(s.ips); i++ {
+		state := s.ips[(s.next+i)%len(s.ips)]
+		s.resetWindow(state, now)
+
+		if at := s.availableAt(state, now); at.After(now) {
+			if fallback == nil || at.Before(fallbackAt) {
+				fallback, fallbackAt = state, at
+			}
+
+			continue
+		}
+
+		s.next = (s.next + i + 1) % len(s.ips)
+		return s.take(state)
+	}
+
+	return s.take(fallback)
+}
+
+// RateLimited puts the ip into cooldown for retryAfter, or the default Cooldown when no Retry-After was given
+func (s *IPScheduler) RateLimited(ip net.IP, retryAfter time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if retryAfter <= 0 {
+		retryAfter = s.Cooldown
+	}
+
+	for _, state := range s.ips {
+		if state.ip.Equal(ip) {
+			state.rateLimited++
+
+			if until := s.now().Add(retryAfter); until.After(state.cooldownUntil) {
+				state.cooldownUntil = until
+			}
+
+			return
+		}
+	}
+}
+
+// State returns a snapshot of every ip of the pool
+func (s *IPScheduler) State() []IPState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	states := make([]IPState, 0, len(s.ips))
+
+	for _, state := range s.ips {
+		s.resetWindow(state, now)
+
+		ipState := IPState{
+			Ip:          state.ip.String(),
+			Used:        state.used,
+			Budget:      s.Budget,
+			WindowReset: state.windowStart.Add(s.Window),
+			CoolingDown: state.cooldownUntil.After(now),
+			Requests:    state.requests,
+			RateLimited: state.rateLimited,
+		}
+
+		if ipState.CoolingDown {
+			ipState.CooldownUntil = state.cooldownUntil
+		}
+
+		states = append(states, ipState)
+	}
+
+	return states
+}
+
+// resetWindow starts a new budget window once the current one has passed
+func (s *IPScheduler) resetWindow(state *ipState, now time.Time) {
+	if state.windowStart.IsZero() || !now.Before(state.windowStart.Add(s.Window)) {
+		state.windowStart = now
+		state.used = 0
+	}
+}
+
+// availableAt returns when the ip may be used again, a time not after now means right away
+func (s *IPScheduler) availableAt(state *ipState, now time.Time) time.Time {
+	at := state.cooldownUntil
+
+	if s.Budget > 0 && state.used >= s.Budget {
+		if reset := state.windowStart.Add(s.Window); reset.After(at) {
+			at = reset
+		}
+	}
+
+	return at
+}
+
+func (s *IPScheduler) take(state *ipState) net.IP {
+	state.used++
+	state.requests++
+
+	return state.ip
+}
@@ -0,0 +1,150 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func newTestScheduler(now *time.Time) *IPScheduler {
+	s := NewIPScheduler([]net.IP{net.ParseIP("127.0.0.2"), net.ParseIP("127.0.0.3")})
+	s.now = func() time.Time {
+		return *now
+	}
+
+	return s
+}
+
+func TestIPSchedulerCooldown(t *testing.T) {
+	now := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	s := newTestScheduler(&now)
+
+	if a, b := s.Next(), s.Next(); a.Equal(b) {
+		t.Fatalf("Next did not rotate, got %s twice", a)
+	}
+
+	//a 429 takes the ip out of rotation until its Retry-After passed
+	s.RateLimited(net.ParseIP("127.0.0.2"), 10*time.Second)
+
+	for i := 0; i < 4; i++ {
+		if ip := s.Next(); !ip.Equal(net.ParseIP("127.0.0.3")) {
+			t.Fatalf("Next = %s while 127.0.0.2 is cooling down", ip)
+		}
+	}
+
+	//with every ip cooling down the one available first is used anyway
+	s.RateLimited(net.ParseIP("127.0.0.3"), time.Minute)
+
+	if ip := s.Next(); !ip.Equal(net.ParseIP("127.0.0.2")) {
+		t.Fatalf("Next = %s, want the ip whose cooldown ends first", ip)
+	}
+
+	now = now.Add(11 * time.Second)
+
+	if ip := s.Next(); !ip.Equal(net.ParseIP("127.0.0.2")) {
+		t.Fatalf("Next = %s after the cooldown of 127.0.0.2 passed", ip)
+	}
+}
+
+func TestIPSchedulerBudget(t *testing.T) {
+	now := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	s := newTestScheduler(&now)
+	s.Budget = 2
+
+	s.Next()
+	s.Next()
+	s.Next()
+	s.Next()
+
+	for _, state := range s.State() {
+		if state.Used != 2 {
+			t.Errorf("%s used %d requests, want 2", state.Ip, state.Used)
+		}
+	}
+
+	//the window resets the budget
+	now = now.Add(DefaultIPWindow)
+	s.Next()
+
+	used := 0
+	for _, state := range s.State() {
+		used += state.Used
+	}
+
+	if used != 1 {
+		t.Errorf("used %d requests after the window reset, want 1", used)
+	}
+}
+
+func TestPingIP(t *testing.T) {
+	now := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	pool := []net.IP{net.ParseIP("127.0.0.2"), net.ParseIP("127.0.0.3")}
+
+	handler := &Handler{IPPool: pool, Scheduler: newTestScheduler(&now)}
+	handler.Scheduler.RateLimited(pool[0], time.Minute)
+
+	//server pings rotate through the whole pool, whatever the mojang cooldowns
+	seen := make(map[string]int)
+	for i := 0; i < 4; i++ {
+		seen[handler.pingIP().String()]++
+	}
+
+	if seen["127.0.0.2"] != 2 || seen["127.0.0.3"] != 2 {
+		t.Errorf("pingIP used %v, want both ips twice", seen)
+	}
+
+	//and leave the mojang budget alone
+	for _, state := range handler.Scheduler.State() {
+		if state.Used != 0 || state.Requests != 0 {
+			t.Errorf("%s used %d requests of its budget after pings, want 0", state.Ip, state.Used)
+		}
+	}
+
+	if ip := (&Handler{}).pingIP(); ip != nil {
+		t.Errorf("pingIP without a pool = %s, want nil", ip)
+	}
+}
+
+func TestGetIPPool(t *testing.T) {
+	handler, server := newFakeHandler(t)
+	handler.AdminKey = "secret"
+	handler.Scheduler = NewIPScheduler([]net.IP{net.ParseIP("127.0.0.2"), net.ParseIP("127.0.0.3")})
+	handler.Mojang.(*FasthttpMojangClient).Retry = NoRetryPolicy
+
+	//a 429 observed by the fetch layer puts the ip into cooldown
+	server.RateLimit(1)
+	handler.FetchUUID(context.Background(), "Notch")
+
+	app := fiber.New()
+	app.Get("/admin/ips", handler.GetIPPool)
+
+	code, _ := doRequest(t, app, httptest.NewRequest(fiber.MethodGet, "/admin/ips", nil))
+	if code != fiber.StatusUnauthorized {
+		t.Errorf("GET /admin/ips without key = %d, want 401", code)
+	}
+
+	req := httptest.NewRequest(fiber.MethodGet, "/admin/ips", nil)
+	req.Header.Set("x-bedgg-api-key", "secret")
+	code, body := doRequest(t, app, req)
+
+	var states []IPState
+	if err := json.Unmarshal(body, &states); code != fiber.StatusOK || err != nil {
+		t.Fatalf("GET /admin/ips = %d %v", code, err)
+	}
+
+	coolingDown := 0
+	for _, state := range states {
+		if state.CoolingDown {
+			coolingDown++
+		}
+	}
+
+	if len(states) != 2 || coolingDown != 1 {
+		t.Errorf("GET /admin/ips = %+v, want one of two ips cooling down", states)
+	}
+}
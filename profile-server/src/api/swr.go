@@ -0,0 +1,336 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// StaleWhileRevalidate how long past its TTL an entry is still served right away while it is refreshed in the background
+const StaleWhileRevalidate = 1 * time.Hour
+
+// StaleIfError how long past its TTL an entry is kept to be served when refreshing it fails
+const StaleIfError = 24 * time.Hour
+
+// immutablePrefixes key prefixes whose values never change once stored, their entries are served until they expire
+// instead of being revalidated
+var immutablePrefixes = []string{"texture:"}
+
+func isImmutable(key string) bool {
+	for _, prefix := range immutablePrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// cacheEntry a cached value with the time it was stored, so its age is known once its TTL passed
+type cacheEntry struct {
+	Value    string `json:"value"`
+	StoredAt int64  `json:"storedAt"`
+	// Missing marks a negative entry, mojang reported there is nothing under this key
+	Missing bool `json:"missing,omitempty"`
+}
+
+func (e *cacheEntry) age() time.Duration {
+	age := time.Since(time.UnixMilli(e.StoredAt))
+
+	if age < 0 {
+		return 0
+	}
+
+	return age
+}
+
+// cacheWrite an entry waiting to be stored, so batch lookups can store all of theirs at once
+type cacheWrite struct {
+	key   string
+	value string
+	ttl   time.Duration
+}
+
+// swrResult the outcome of resolving a key through swrResolve
+type swrResult struct {
+	code  int
+	value string
+	age   time.Duration
+	errs  []error
+	// write the entry to store for key, nil when the cache is left as is
+	write *cacheWrite
+}
+
+// decodeEntry parses an entry read from the cache, nil when it can not be parsed
+func (h *Handler) decodeEntry(key string, item string) *cacheEntry {
+	entry := &cacheEntry{}
+	err := json.Unmarshal([]byte(item), entry)
+
+	if err != nil {
+		//entries written before they carried their age are treated as missing
+		h.Logger.Error("[%s] Failed to unmarshall cache entry: %v", key, err)
+		return nil
+	}
+
+	return entry
+}
+
+// cacheGetEntry returns the entry stored under key, nil when there is none
+func (h *Handler) cacheGetEntry(ctx context.Context, key string) (*cacheEntry, error) {
+	exists, item, err := h.CacheGet(ctx, key)
+
+	if err != nil || !exists {
+		return nil, err
+	}
+
+	return h.decodeEntry(key, item), nil
+}
+
+// entryWrite prepares storing value under key, keeping it past its ttl so it can be served stale
+func entryWrite(key string, value string, ttl time.Duration) (*cacheWrite, error) {
+	out, err := json.Marshal(&cacheEntry{
+		Value:    value,
+		StoredAt: time.Now().UnixMilli(),
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &cacheWrite{key, string(out), ttl + StaleIfError}, nil
+}
+
+// cachePutEntry stores value under key, keeping it past its ttl so it can be served stale
+func (h *Handler) cachePutEntry(ctx context.Context, key string, value string, ttl time.Duration) error {
+	write, err := entryWrite(key, value, ttl)
+
+	if err != nil {
+		return err
+	}
+
+	return h.CachePut(ctx, write.key, write.value, write.ttl)
+}
+
+// cachePutWrites stores the writes, batching the ones sharing a ttl into a single call
+func (h *Handler) cachePutWrites(ctx context.Context, writes []*cacheWrite) {
+	byTTL := make(map[time.Duration]map[string]string)
+	var keys []string
+
+	for _, write := range writes {
+		if write == nil {
+			continue
+		}
+
+		if byTTL[write.ttl] == nil {
+			byTTL[write.ttl] = make(map[string]string)
+		}
+
+		byTTL[write.ttl][write.key] = write.value
+		keys = append(keys, write.key)
+	}
+
+	for ttl, items := range byTTL {
+		//CacheMultiPut logs the failure, the values were fetched and are served regardless
+		_ = h.CacheMultiPut(ctx, items, ttl)
+	}
+
+	h.releaseLocks(keys...)
+}
+
+// swr resolves key through the cache: fresh entries are returned as is, entries within StaleWhileRevalidate past
+// their ttl are returned while being refreshed in the background and older entries are only returned when fetching fails.
+// fetch is called with ctx on a miss and with the server context Ctx when revalidating in the background.
+// Returns the status code, the value and its age
+func (h *Handler) swr(ctx context.Context, key string, ttl time.Duration, fetch func(ctx context.Context) (int, string, []error)) (int, string, time.Duration, []error) {
+	entry, err := h.cacheGetEntry(ctx, key)
+
+	if err != nil {
+		return fiber.StatusInternalServerError, "", 0, []error{err}
+	}
+
+	result := h.swrResolve(ctx, key, ttl, entry, fetch)
+
+	if result.write != nil {
+		err = h.CachePut(ctx, result.write.key, result.write.value, result.write.ttl)
+		if err != nil {
+			h.Logger.Error("[%s] Failed to cache item: %v", key, err)
+		}
+
+		h.releaseLocks(key)
+	}
+
+	return result.code, result.value, result.age, result.errs
+}
+
+// swrMulti resolves every key like swr, reading all of them from the cache at once and storing what was fetched
+// at once. fetch is called with the index of the key it fetches, each call waiting for a slot of the Limiter.
+// Keys still unresolved when ctx is done fail with 504, what they fetch later is cached in the background.
+// The results are in the order of keys
+func (h *Handler) swrMulti(ctx context.Context, keys []string, ttl time.Duration, fetch func(ctx context.Context, i int) (int, string, []error)) []*swrResult {
+	results := make([]*swrResult, len(keys))
+	items, err := h.CacheMultiGet(ctx, keys)
+
+	if err != nil {
+		for i := range keys {
+			results[i] = &swrResult{code: fiber.StatusInternalServerError, errs: []error{err}}
+		}
+
+		return results
+	}
+
+	type resolved struct {
+		i      int
+		result *swrResult
+	}
+
+	done := make(chan resolved, len(keys))
+
+	for i, key := range keys {
+		var entry *cacheEntry
+		if item, ok := items[key]; ok {
+			entry = h.decodeEntry(key, item)
+		}
+
+		go func(i int, key string, entry *cacheEntry) {
+			done <- resolved{i, h.swrResolve(ctx, key, ttl, entry, func(ctx context.Context) (int, string, []error) {
+				return h.limitFetch(ctx, key, func() (int, string, []error) {
+					return fetch(ctx, i)
+				})
+			})}
+		}(i, key, entry)
+	}
+
+	var writes []*cacheWrite
+	pending := len(keys)
+
+collect:
+	for pending > 0 {
+		select {
+		case r := <-done:
+			results[r.i] = r.result
+			writes = append(writes, r.result.write)
+			pending--
+		case <-ctx.Done():
+			break collect
+		}
+	}
+
+	//what was fetched is worth keeping even when the request ran out of time, so it is stored with the server context
+	h.cachePutWrites(h.Ctx, writes)
+
+	if pending == 0 {
+		return results
+	}
+
+	for i, result := range results {
+		if result == nil {
+			h.Logger.Error("[%s] Abandoned lookup: %v", keys[i], ctx.Err())
+			results[i] = &swrResult{code: fiber.StatusGatewayTimeout, errs: []error{}}
+		}
+	}
+
+	//the fetches already running still fill the cache for the next request
+	go func(pending int) {
+		writes := make([]*cacheWrite, pending)
+
+		for i := range writes {
+			writes[i] = (<-done).result.write
+		}
+
+		h.cachePutWrites(h.Ctx, writes)
+	}(pending)
+
+	return results
+}
+
+// swrResolve decides what to serve for key given its cached entry, fetching it when the entry is missing or too old
+func (h *Handler) swrResolve(ctx context.Context, key string, ttl time.Duration, entry *cacheEntry, fetch func(ctx context.Context) (int, string, []error)) *swrResult {
+	if entry != nil {
+		age := entry.age()
+
+		if entry.Missing {
+			h.Logger.Info("[%s] Cache Hit, missing", key)
+			return &swrResult{code: fiber.StatusNotFound, age: age, errs: []error{}}
+		}
+
+		if age < ttl || isImmutable(key) {
+			h.Logger.Info("[%s] Cache Hit", key)
+			return &swrResult{code: fiber.StatusOK, value: entry.Value, age: age, errs: []error{}}
+		}
+
+		if age < ttl+StaleWhileRevalidate {
+			h.Logger.Info("[%s] Cache Stale, revalidating", key)
+			go h.revalidate(key, ttl, fetch)
+
+			return &swrResult{code: fiber.StatusOK, value: entry.Value, age: age, errs: []error{}}
+		}
+	}
+
+	h.Logger.Info("[%s] Cache Miss", key)
+	code, value, errs := fetch(ctx)
+
+	//remember what mojang does not know, so repeated lookups of it stay off the upstream
+	if len(errs) == 0 && isNotFound(code) {
+		return &swrResult{code: fiber.StatusNotFound, errs: []error{}, write: h.missingWrite(key)}
+	}
+
+	if len(errs) > 0 || code != fiber.StatusOK {
+		//stale-if-error, a failing or rate limited upstream is hidden behind the last known value
+		if entry != nil && (len(errs) > 0 || code == fiber.StatusTooManyRequests || code >= 500) {
+			for _, err := range errs {
+				h.Logger.Error("[%s] Serving stale entry: %v", key, err)
+			}
+
+			return &swrResult{code: fiber.StatusOK, value: entry.Value, age: entry.age(), errs: []error{}}
+		}
+
+		return &swrResult{code: code, errs: errs}
+	}
+
+	write, err := entryWrite(key, value, ttl)
+	if err != nil {
+		h.Logger.Error("[%s] Failed to cache item: %v", key, err)
+	}
+
+	return &swrResult{code: fiber.StatusOK, value: value, errs: []error{}, write: write}
+}
+
+// revalidate refreshes a stale entry, keeping the stale one when fetching fails. It outlives the request that
+// found the entry stale, so it runs with the server context Ctx
+func (h *Handler) revalidate(key string, ttl time.Duration, fetch func(ctx context.Context) (int, string, []error)) {
+	code, value, errs := fetch(h.Ctx)
+
+	if len(errs) == 0 && isNotFound(code) {
+		h.cacheMissing(h.Ctx, key)
+		return
+	}
+
+	if len(errs) > 0 || code != fiber.StatusOK {
+		h.Logger.Error("[%s] Failed to revalidate, status %d: %v", key, code, errs)
+		return
+	}
+
+	err := h.cachePutEntry(h.Ctx, key, value, ttl)
+	if err != nil {
+		h.Logger.Error("[%s] Failed to cache item: %v", key, err)
+	}
+
+	h.releaseLocks(key)
+}
+
+// setCacheHeaders tells the client the age of the response and how much longer it may reuse it
+func setCacheHeaders(c *fiber.Ctx, age time.Duration, ttl time.Duration) {
+	maxAge := ttl - age
+
+	if maxAge < 0 {
+		maxAge = 0
+	}
+
+	c.Set(fiber.HeaderAge, strconv.Itoa(int(age.Seconds())))
+	c.Set(fiber.HeaderCacheControl, fmt.Sprintf("private, max-age=%d", int(maxAge.Seconds())))
+}
@@ -0,0 +1,147 @@
+package api
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"bed.gg/minecraft-api/v2/src/mojangtest"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v9"
+	"github.com/gofiber/fiber/v2"
+)
+
+// putAgedEntry stores a profile of the fake api in the cache as if it was cached age ago
+func putAgedEntry(t *testing.T, handler *Handler, playerUUID UUID, age time.Duration) {
+	_, _, body, errs := handler.FetchProfile(context.Background(), playerUUID)
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	out, _ := json.Marshal(&cacheEntry{Value: string(body), StoredAt: time.Now().Add(-age).UnixMilli()})
+	if err := handler.CachePut(context.Background(), profileKey(playerUUID), string(out), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newSWRApp(t *testing.T) (*fiber.App, *Handler, *mojangtest.Server) {
+	handler, server := newFakeHandler(t)
+	handler.Mojang.(*FasthttpMojangClient).Retry = NoRetryPolicy
+
+	mr := miniredis.RunT(t)
+	handler.Rdb = redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	handler.Ctx = context.Background()
+
+	app := fiber.New()
+	app.Get("/profile/:uuid", handler.GetProfile)
+
+	return app, handler, server
+}
+
+func TestStaleWhileRevalidate(t *testing.T) {
+	app, handler, server := newSWRApp(t)
+	putAgedEntry(t, handler, UUID(notch.Id), TTL+time.Minute)
+	before := server.Requests()
+
+	res, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/profile/"+notch.Id, nil), -1)
+	if err != nil || res.StatusCode != fiber.StatusOK {
+		t.Fatalf("GET stale profile = %v %v", res, err)
+	}
+
+	if age, _ := strconv.Atoi(res.Header.Get(fiber.HeaderAge)); age < int((TTL + time.Minute).Seconds()) {
+		t.Errorf("Age = %s, want the age of the stale entry", res.Header.Get(fiber.HeaderAge))
+	}
+
+	if res.Header.Get(fiber.HeaderCacheControl) != "private, max-age=0" {
+		t.Errorf("Cache-Control = %s, want max-age=0 for a stale entry", res.Header.Get(fiber.HeaderCacheControl))
+	}
+
+	//the stale entry is refreshed in the background
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		entry, _ := handler.cacheGetEntry(context.Background(), profileKey(UUID(notch.Id)))
+
+		if entry != nil && entry.age() < TTL {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatal("stale entry was not revalidated")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if server.Requests()-before != 1 {
+		t.Errorf("revalidation made %d upstream requests, want 1", server.Requests()-before)
+	}
+}
+
+func TestStaleIfError(t *testing.T) {
+	_, handler, server := newSWRApp(t)
+
+	//past the revalidation window the entry is only served when the upstream fails
+	putAgedEntry(t, handler, UUID(jeb.Id), TTL+StaleWhileRevalidate+time.Minute)
+	server.Fail(1, fiber.StatusServiceUnavailable)
+
+	code, profile, _, age, errs := handler.lookupProfile(context.Background(), UUID(jeb.Id))
+	if code != fiber.StatusOK || profile == nil || age < TTL+StaleWhileRevalidate {
+		t.Fatalf("lookupProfile during outage = %d %v %v", code, age, errs)
+	}
+
+	//once the upstream is back the entry is refreshed synchronously
+	code, profile, _, age, _ = handler.lookupProfile(context.Background(), UUID(jeb.Id))
+	if code != fiber.StatusOK || profile == nil || age != 0 {
+		t.Fatalf("lookupProfile after outage = %d %v", code, age)
+	}
+
+	//without a stale entry the upstream failure surfaces
+	server.Fail(1, fiber.StatusServiceUnavailable)
+
+	code, profile, _, _, _ = handler.lookupProfile(context.Background(), UUID(notch.Id))
+	if code != fiber.StatusServiceUnavailable || profile != nil {
+		t.Errorf("lookupProfile without stale entry = %d, want 503", code)
+	}
+}
+
+func TestImmutableTextures(t *testing.T) {
+	app, handler, server := newSWRApp(t)
+	app.Get("/texture/:textureid", handler.GetTexture)
+
+	//a texture entry far past the profile ttl is still served as is, without asking the upstream again
+	textureid := server.SkinTextureId(notch.Id)
+	texture, _ := server.Texture(textureid)
+	age := TTL + StaleWhileRevalidate + time.Minute
+
+	out, _ := json.Marshal(&cacheEntry{Value: base64.StdEncoding.EncodeToString(texture), StoredAt: time.Now().Add(-age).UnixMilli()})
+	if err := handler.CachePut(context.Background(), textureKey(textureid), string(out), TextureTTL); err != nil {
+		t.Fatal(err)
+	}
+
+	before := server.Requests()
+
+	code, body := doRequest(t, app, httptest.NewRequest(fiber.MethodGet, "/texture/"+textureid, nil))
+	if code != fiber.StatusOK || string(body) != base64.StdEncoding.EncodeToString(texture) {
+		t.Fatalf("GET old texture = %d", code)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+
+	if server.Requests() != before {
+		t.Errorf("old texture made %d upstream requests, want none", server.Requests()-before)
+	}
+
+	//fetched textures are kept for the TextureTTL
+	other := server.SkinTextureId(jeb.Id)
+	if code, _ := doRequest(t, app, httptest.NewRequest(fiber.MethodGet, "/texture/"+other, nil)); code != fiber.StatusOK {
+		t.Fatalf("GET texture = %d", code)
+	}
+
+	if _, ttl, err := handler.cache().TTL(context.Background(), textureKey(other)); err != nil || ttl <= TextureTTL {
+		t.Errorf("texture ttl = %v %v, want more than %v", ttl, err, TextureTTL)
+	}
+}
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// SteveTextureId texture id of the classic default skin
+const SteveTextureId = "1a4af718455d4aab528e7a61f86fa25e6a369d1768dcb13f7df319a713eb810b"
+
+// AlexTextureId texture id of the slim default skin
+const AlexTextureId = "3b60a1f6d562f52aaebbf1434f1de147933a3affe0e764fa49ea057536623cd3"
+
+type TextureResponse struct {
+	Timestamp         int64  `json:"timestamp"`
+	ProfileId         string `json:"profileId"`
+	ProfileName       string `json:"profileName"`
+	SignatureRequired bool   `json:"signatureRequired"`
+	Textures          struct {
+		Skin SkinURL `json:"SKIN"`
+		Cape CapeURL `json:"CAPE"`
+	} `json:"textures"`
+}
+
+type SkinURL struct {
+	Url      string `json:"url"`
+	Metadata struct {
+		Model string `json:"model"`
+	} `json:"metadata"`
+}
+
+type CapeURL struct {
+	Url string `json:"url"`
+}
+
+// DecodedProfile a profile with its textures property decoded into plain fields
+type DecodedProfile struct {
+	Id            string `json:"id"`
+	Name          string `json:"name"`
+	SkinUrl       string `json:"skinUrl"`
+	SkinTextureId string `json:"skinTextureId"`
+	Model         string `json:"model"`
+	CapeUrl       string `json:"capeUrl,omitempty"`
+	CapeTextureId string `json:"capeTextureId,omitempty"`
+	Timestamp     int64  `json:"timestamp"`
+	Verified      bool   `json:"verified"`
+}
+
+// DecodeTextures decodes the base64 textures property of the profile
+func (p *ProfileResponse) DecodeTextures() (*TextureResponse, error) {
+	for _, property := range p.Properties {
+		if property.Name != "textures" {
+			continue
+		}
+
+		textureDataJsonString, err := base64.StdEncoding.DecodeString(property.Value)
+
+		if err != nil {
+			return nil, err
+		}
+
+		textureResponse := &TextureResponse{}
+		err = json.Unmarshal(textureDataJsonString, textureResponse)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return textureResponse, nil
+	}
+
+	return nil, errors.New("profile has no textures property")
+}
+
+// TextureId helper method to extract the texture id from a textures.minecraft.net url
+func TextureId(url string) string {
+	if url == "" {
+		return ""
+	}
+
+	splitString := strings.Split(url, "/")
+	return splitString[len(splitString)-1]
+}
+
+// SkinTexture returns the texture id and model of the skin, falling back to the default skin of the player
+func (t *TextureResponse) SkinTexture() (string, bool) {
+	if t.Textures.Skin.Url != "" {
+		return TextureId(t.Textures.Skin.Url), t.Textures.Skin.Metadata.Model == "slim"
+	}
+
+	//players without a custom skin get steve or alex based on the hash of their uuid
+	u, err := uuid.Parse(t.ProfileId)
+
+	if err != nil {
+		return SteveTextureId, false
+	}
+
+	var most, least int64
+	for i := 0; i < 8; i++ {
+		most = most<<8 | int64(u[i])
+		least = least<<8 | int64(u[i+8])
+	}
+
+	hilo := most ^ least
+	if (int32(hilo>>32)^int32(hilo))&1 == 1 {
+		return AlexTextureId, true
+	}
+
+	return SteveTextureId, false
+}
+
+// Decode flattens the profile and its textures property into a DecodedProfile
+func (p *ProfileResponse) Decode() (*DecodedProfile, error) {
+	textureResponse, err := p.DecodeTextures()
+
+	if err != nil {
+		return nil, err
+	}
+
+	skinTextureId, slim := textureResponse.SkinTexture()
+
+	decodedProfile := &DecodedProfile{
+		Id:            p.Id,
+		Name:          p.Name,
+		SkinUrl:       textureResponse.Textures.Skin.Url,
+		SkinTextureId: skinTextureId,
+		Model:         "classic",
+		CapeUrl:       textureResponse.Textures.Cape.Url,
+		CapeTextureId: TextureId(textureResponse.Textures.Cape.Url),
+		Timestamp:     textureResponse.Timestamp,
+	}
+
+	if slim {
+		decodedProfile.Model = "slim"
+	}
+
+	//players on a default skin have no url in their textures property
+	if decodedProfile.SkinUrl == "" {
+		decodedProfile.SkinUrl = fmt.Sprintf("http://textures.minecraft.net/texture/%s", skinTextureId)
+	}
+
+	return decodedProfile, nil
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// UUID a player uuid in its canonical form, undashed and lowercase like the mojang api returns it,
+// so dashed, undashed and uppercase variants of the same player share cache entries and search documents
+type UUID string
+
+// ParseUUID parses a dashed or undashed uuid in any case into its canonical form
+func ParseUUID(u string) (UUID, error) {
+	parsed, err := uuid.Parse(u)
+
+	if err != nil {
+		return "", err
+	}
+
+	return UUID(undashed(parsed)), nil
+}
+
+func (u UUID) String() string {
+	return string(u)
+}
+
+// Dashed returns the uuid in the dashed form
+func (u UUID) Dashed() string {
+	dashed, _ := DashedUUID(string(u))
+	return dashed
+}
+
+type UUIDFormatResponse struct {
+	Id     string `json:"id"`
+	Dashed string `json:"dashed"`
+}
+
+type OfflineUUIDResponse struct {
+	Name   string `json:"name"`
+	Id     string `json:"id"`
+	Dashed string `json:"dashed"`
+}
+
+// OfflineUUID computes the uuid offline mode servers assign to a username,
+// the name based v3 uuid of "OfflinePlayer:<username>" as produced by java's UUID.nameUUIDFromBytes
+func OfflineUUID(username string) uuid.UUID {
+	hash := md5.Sum([]byte("OfflinePlayer:" + username))
+
+	//set the version to 3 and the variant to IETF
+	hash[6] = hash[6]&0x0f | 0x30
+	hash[8] = hash[8]&0x3f | 0x80
+
+	return hash
+}
+
+// DashedUUID converts a dashed or undashed uuid to the dashed lowercase form
+func DashedUUID(u string) (string, error) {
+	parsed, err := uuid.Parse(u)
+
+	if err != nil {
+		return "", err
+	}
+
+	return parsed.String(), nil
+}
+
+// UndashedUUID converts a dashed or undashed uuid to the undashed lowercase form used by the mojang api
+func UndashedUUID(u string) (string, error) {
+	parsed, err := uuid.Parse(u)
+
+	if err != nil {
+		return "", err
+	}
+
+	return undashed(parsed), nil
+}
+
+func undashed(u uuid.UUID) string {
+	return hex.EncodeToString(u[:])
+}
+
+// formatUUID builds the UUIDFormatResponse of a parsed uuid
+func formatUUID(u uuid.UUID) *UUIDFormatResponse {
+	return &UUIDFormatResponse{
+		Id:     undashed(u),
+		Dashed: strings.ToLower(u.String()),
+	}
+}
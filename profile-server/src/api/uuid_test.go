@@ -0,0 +1,45 @@
+package api
+
+import "testing"
+
+func TestOfflineUUID(t *testing.T) {
+	//uuid assigned to Notch by offline mode servers
+	if got := OfflineUUID("Notch").String(); got != "b50ad385-829d-3141-a216-7e7d7539ba7f" {
+		t.Errorf("unexpected offline uuid %s", got)
+	}
+}
+
+func TestUUIDFormat(t *testing.T) {
+	for _, input := range []string{"069a79f444e94726a5befca90e38aaf5", "069A79F4-44E9-4726-A5BE-FCA90E38AAF5"} {
+		dashed, err := DashedUUID(input)
+		if err != nil || dashed != "069a79f4-44e9-4726-a5be-fca90e38aaf5" {
+			t.Errorf("unexpected dashed uuid %s for %s: %v", dashed, input, err)
+		}
+
+		undashed, err := UndashedUUID(input)
+		if err != nil || undashed != "069a79f444e94726a5befca90e38aaf5" {
+			t.Errorf("unexpected undashed uuid %s for %s: %v", undashed, input, err)
+		}
+	}
+}
+
+func TestParseUUID(t *testing.T) {
+	for _, input := range []string{"069a79f444e94726a5befca90e38aaf5", "069A79F4-44E9-4726-A5BE-FCA90E38AAF5", "069a79f4-44e9-4726-a5be-fca90e38aaf5"} {
+		playerUUID, err := ParseUUID(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if playerUUID != "069a79f444e94726a5befca90e38aaf5" {
+			t.Errorf("ParseUUID(%s) = %s", input, playerUUID)
+		}
+
+		if playerUUID.Dashed() != "069a79f4-44e9-4726-a5be-fca90e38aaf5" {
+			t.Errorf("Dashed(%s) = %s", input, playerUUID.Dashed())
+		}
+	}
+
+	if _, err := ParseUUID("notauuid"); err == nil {
+		t.Error("expected error for bad uuid")
+	}
+}
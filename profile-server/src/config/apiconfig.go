@@ -0,0 +1,65 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+)
+
+// Api optional settings of the api handler, missing fields keep their defaults
+var Api = ApiConfig{
+	YggdrasilPublicKey: "",
+}
+
+type ApiConfig struct {
+	// YggdrasilPublicKey path to the PEM encoded yggdrasil session public key used to verify profile signatures,
+	// empty fetches the key mojang publishes at PublicKeysUrl
+	YggdrasilPublicKey string `json:"yggdrasilPublicKey"`
+	// PublicKeysUrl url of the public keys signing profile properties, empty uses api.minecraftservices.com/publickeys
+	PublicKeysUrl string `json:"publicKeysUrl"`
+	// SessionServerUrl base url of the session server, empty uses sessionserver.mojang.com
+	SessionServerUrl string `json:"sessionServerUrl"`
+	// MojangApiUrl base url of the mojang api, empty uses api.mojang.com
+	MojangApiUrl string `json:"mojangApiUrl"`
+	// TexturesUrl base url of the texture server, empty uses textures.minecraft.net
+	TexturesUrl string `json:"texturesUrl"`
+	// AdminKey value of the x-bedgg-api-key header required by the admin routes, empty disables them
+	AdminKey string `json:"adminKey"`
+	// DistributedLock coalesces upstream fetches across instances sharing the redis, not just within one instance
+	DistributedLock bool `json:"distributedLock"`
+	// NegativeTTL seconds players, names and textures mojang reported missing are cached, 0 uses the default
+	NegativeTTL int `json:"negativeTTL"`
+	// MaxConcurrentFetches how many upstream fetches of batch requests run at once across all requests, 0 uses the default
+	MaxConcurrentFetches int `json:"maxConcurrentFetches"`
+	// MaxBatchSize how many ids a batch request may ask for, 0 uses the default
+	MaxBatchSize int `json:"maxBatchSize"`
+	// BatchTimeout seconds a batch request may take before its outstanding fetches are abandoned, 0 uses the default
+	BatchTimeout int `json:"batchTimeout"`
+	// DialTimeout seconds connecting to an upstream may take, 0 uses the default
+	DialTimeout int `json:"dialTimeout"`
+	// ReadTimeout seconds a single upstream request may take, also bounding meilisearch calls, 0 uses the default
+	ReadTimeout int `json:"readTimeout"`
+	// RequestTimeout seconds a request may take before the upstream work done for it is abandoned, 0 uses the default
+	RequestTimeout int `json:"requestTimeout"`
+	// Cache backend of the cache, "redis" by default or "memory" to run a single instance without redis
+	Cache string `json:"cache"`
+	// LocalCacheSize bytes of the in-process cache in front of redis, 0 uses the default and negative disables it
+	LocalCacheSize int `json:"localCacheSize"`
+}
+
+func init() {
+	f, err := os.Open("config/api.json")
+
+	if os.IsNotExist(err) {
+		return
+	}
+
+	if err != nil {
+		panic(err)
+	}
+
+	err = json.NewDecoder(f).Decode(&Api)
+
+	if err != nil {
+		panic(err)
+	}
+}
@@ -11,6 +11,12 @@ var Ip = Config{
 
 type Config struct {
 	Pool []string
+	// Budget requests per ip per window, 0 keeps the default of the scheduler and below 0 disables the budget
+	Budget int `json:"budget"`
+	// Window seconds the budget is counted over, 0 keeps the default
+	Window int `json:"window"`
+	// Cooldown seconds an ip is skipped after a 429 without a Retry-After, 0 keeps the default
+	Cooldown int `json:"cooldown"`
 }
 
 func init() {
@@ -0,0 +1,425 @@
+// Package mojangtest runs a local stand-in for the mojang apis so the api can be tested without the internet
+package mojangtest
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// BatchSize maximum number of usernames accepted by the batch names endpoint
+const BatchSize = 10
+
+var uuidPattern = regexp.MustCompile("^[a-f0-9]{32}$")
+
+// Player a seeded player of the fake api
+type Player struct {
+	// Id undashed uuid of the player
+	Id   string
+	Name string
+	// Slim gives the player a skin on the slim model
+	Slim bool
+	// DefaultSkin leaves the player without a custom skin, like a new account
+	DefaultSkin bool
+	// Cape gives the player a cape
+	Cape bool
+}
+
+// Server a fake mojang api serving the session server, name lookup, batch names and textures endpoints
+type Server struct {
+	*httptest.Server
+
+	// Key signs the profile properties like the yggdrasil session key
+	Key *rsa.PrivateKey
+
+	mu       sync.RWMutex
+	players  map[string]Player
+	names    map[string]string
+	textures map[string][]byte
+	skins    map[string]string
+	capes    map[string]string
+	latency  time.Duration
+
+	retryAfter  string
+	rateLimited int64
+	failing     int64
+	failCode    int64
+	requests    int64
+}
+
+// NewServer starts a fake mojang api seeded with the given players
+func NewServer(players ...Player) *Server {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+
+	if err != nil {
+		panic(err)
+	}
+
+	s := &Server{
+		Key:      key,
+		players:  make(map[string]Player),
+		names:    make(map[string]string),
+		textures: make(map[string][]byte),
+		skins:    make(map[string]string),
+		capes:    make(map[string]string),
+	}
+
+	for _, player := range players {
+		s.AddPlayer(player)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/session/minecraft/profile/", s.handleProfile)
+	mux.HandleFunc("/users/profiles/minecraft/", s.handleName)
+	mux.HandleFunc("/profiles/minecraft", s.handleNames)
+	mux.HandleFunc("/texture/", s.handleTexture)
+	mux.HandleFunc("/json", s.handleIP)
+
+	s.Server = httptest.NewServer(s.middleware(mux))
+
+	return s
+}
+
+// AddPlayer seeds a player, generating its skin and cape textures
+func (s *Server) AddPlayer(player Player) {
+	player.Id = strings.ToLower(strings.ReplaceAll(player.Id, "-", ""))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.players[player.Id] = player
+	s.names[strings.ToLower(player.Name)] = player.Id
+
+	if !player.DefaultSkin {
+		s.skins[player.Id] = s.addTexture(generateTexture(player.Id, "skin", 64, 64))
+	}
+
+	if player.Cape {
+		s.capes[player.Id] = s.addTexture(generateTexture(player.Id, "cape", 64, 32))
+	}
+}
+
+// SkinTextureId returns the texture id of the generated skin of the player, empty for players on a default skin
+func (s *Server) SkinTextureId(id string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.skins[strings.ToLower(strings.ReplaceAll(id, "-", ""))]
+}
+
+// CapeTextureId returns the texture id of the generated cape of the player, empty for players without a cape
+func (s *Server) CapeTextureId(id string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.capes[strings.ToLower(strings.ReplaceAll(id, "-", ""))]
+}
+
+// Texture returns the png of a texture served by the fake api
+func (s *Server) Texture(textureid string) ([]byte, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	texture, ok := s.textures[textureid]
+	return texture, ok
+}
+
+// SetLatency delays every response by d
+func (s *Server) SetLatency(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.latency = d
+}
+
+// RateLimit answers the next n requests with 429 Too Many Requests
+func (s *Server) RateLimit(n int) {
+	atomic.StoreInt64(&s.rateLimited, int64(n))
+}
+
+// SetRetryAfter sends a Retry-After header of the given number of seconds with rate limited responses
+func (s *Server) SetRetryAfter(seconds int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.retryAfter = ""
+	if seconds > 0 {
+		s.retryAfter = fmt.Sprint(seconds)
+	}
+}
+
+// Fail answers the next n requests with the given status code, like an upstream outage
+func (s *Server) Fail(n int, code int) {
+	atomic.StoreInt64(&s.failCode, int64(code))
+	atomic.StoreInt64(&s.failing, int64(n))
+}
+
+// Requests returns the number of requests served so far
+func (s *Server) Requests() int {
+	return int(atomic.LoadInt64(&s.requests))
+}
+
+// TextureId the id mojang gives a texture, the sha256 of its png
+func TextureId(texture []byte) string {
+	sum := sha256.Sum256(texture)
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *Server) addTexture(texture []byte) string {
+	textureid := TextureId(texture)
+	s.textures[textureid] = texture
+
+	return textureid
+}
+
+func (s *Server) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&s.requests, 1)
+
+		s.mu.RLock()
+		latency := s.latency
+		retryAfter := s.retryAfter
+		s.mu.RUnlock()
+
+		if latency > 0 {
+			time.Sleep(latency)
+		}
+
+		if take(&s.failing) {
+			writeError(w, int(atomic.LoadInt64(&s.failCode)), "", "The service is unavailable")
+			return
+		}
+
+		if take(&s.rateLimited) {
+			if retryAfter != "" {
+				w.Header().Set("Retry-After", retryAfter)
+			}
+
+			writeError(w, http.StatusTooManyRequests, "TooManyRequestsException", "The client has sent too many requests within a certain amount of time")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// take consumes one request of a burst, reporting false once the burst is used up
+func take(remaining *int64) bool {
+	for {
+		n := atomic.LoadInt64(remaining)
+
+		if n <= 0 {
+			return false
+		}
+
+		if atomic.CompareAndSwapInt64(remaining, n, n-1) {
+			return true
+		}
+	}
+}
+
+func (s *Server) handleProfile(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/session/minecraft/profile/")
+
+	if !uuidPattern.MatchString(id) {
+		writeError(w, http.StatusBadRequest, "IllegalArgumentException", fmt.Sprintf("Invalid UUID string: %s", id))
+		return
+	}
+
+	s.mu.RLock()
+	player, ok := s.players[id]
+	s.mu.RUnlock()
+
+	//the session server answers unknown players with an empty 204
+	if !ok {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	textures := map[string]interface{}{}
+
+	if !player.DefaultSkin {
+		skin := map[string]interface{}{
+			"url": fmt.Sprintf("http://textures.minecraft.net/texture/%s", s.SkinTextureId(player.Id)),
+		}
+
+		if player.Slim {
+			skin["metadata"] = map[string]string{"model": "slim"}
+		}
+
+		textures["SKIN"] = skin
+	}
+
+	if player.Cape {
+		textures["CAPE"] = map[string]string{
+			"url": fmt.Sprintf("http://textures.minecraft.net/texture/%s", s.CapeTextureId(player.Id)),
+		}
+	}
+
+	value, _ := json.Marshal(map[string]interface{}{
+		"timestamp":   time.Now().UnixMilli(),
+		"profileId":   player.Id,
+		"profileName": player.Name,
+		"textures":    textures,
+	})
+
+	property := map[string]string{
+		"name":  "textures",
+		"value": base64.StdEncoding.EncodeToString(value),
+	}
+
+	if r.URL.Query().Get("unsigned") == "false" {
+		property["signature"] = s.sign(property["value"])
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"id":         player.Id,
+		"name":       player.Name,
+		"properties": []map[string]string{property},
+	})
+}
+
+func (s *Server) handleName(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/users/profiles/minecraft/")
+
+	s.mu.RLock()
+	id, ok := s.names[strings.ToLower(name)]
+	player := s.players[id]
+	s.mu.RUnlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, "", fmt.Sprintf("Couldn't find any profile with name %s", name))
+		return
+	}
+
+	writeJSON(w, map[string]string{
+		"id":   player.Id,
+		"name": player.Name,
+	})
+}
+
+func (s *Server) handleNames(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var names []string
+
+	if err := json.NewDecoder(r.Body).Decode(&names); err != nil {
+		writeError(w, http.StatusBadRequest, "IllegalArgumentException", err.Error())
+		return
+	}
+
+	if len(names) > BatchSize {
+		writeError(w, http.StatusBadRequest, "IllegalArgumentException", fmt.Sprintf("Not more that %d profile name per call is allowed.", BatchSize))
+		return
+	}
+
+	//unknown names are left out of the response
+	found := []map[string]string{}
+
+	s.mu.RLock()
+	for _, name := range names {
+		if id, ok := s.names[strings.ToLower(name)]; ok {
+			found = append(found, map[string]string{
+				"id":   id,
+				"name": s.players[id].Name,
+			})
+		}
+	}
+	s.mu.RUnlock()
+
+	writeJSON(w, found)
+}
+
+func (s *Server) handleTexture(w http.ResponseWriter, r *http.Request) {
+	texture, ok := s.Texture(strings.TrimPrefix(r.URL.Path, "/texture/"))
+
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	_, _ = w.Write(texture)
+}
+
+// handleIP echoes the address the request came from, like ipinfo.io/json
+func (s *Server) handleIP(w http.ResponseWriter, r *http.Request) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	writeJSON(w, map[string]string{
+		"ip": host,
+	})
+}
+
+func (s *Server) sign(value string) string {
+	digest := sha1.Sum([]byte(value))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.Key, crypto.SHA1, digest[:])
+
+	if err != nil {
+		panic(err)
+	}
+
+	return base64.StdEncoding.EncodeToString(signature)
+}
+
+// generateTexture paints a deterministic png for the player so every player has a distinct texture
+func generateTexture(id string, kind string, w int, h int) []byte {
+	seed := md5.Sum([]byte(kind + id))
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			//8x8 blocks of the colors derived from the seed
+			i := ((x / 8) + (y/8)*(w/8)) % (len(seed) - 2)
+			img.SetNRGBA(x, y, color.NRGBA{R: seed[i], G: seed[i+1], B: seed[i+2], A: 0xff})
+		}
+	}
+
+	buf := &bytes.Buffer{}
+
+	if err := png.Encode(buf, img); err != nil {
+		panic(err)
+	}
+
+	return buf.Bytes()
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, name string, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"error":        name,
+		"errorMessage": message,
+	})
+}
@@ -0,0 +1,368 @@
+package motd
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"strings"
+)
+
+// Component a minecraft chat component, the format of server descriptions since 1.7
+type Component struct {
+	Text          string      `json:"text"`
+	Translate     string      `json:"translate"`
+	Color         string      `json:"color"`
+	Bold          *bool       `json:"bold"`
+	Italic        *bool       `json:"italic"`
+	Underlined    *bool       `json:"underlined"`
+	Strikethrough *bool       `json:"strikethrough"`
+	Obfuscated    *bool       `json:"obfuscated"`
+	Extra         []Component `json:"extra"`
+}
+
+// UnmarshalJSON accepts the shorthand forms of a component: a plain string or an array of components
+func (c *Component) UnmarshalJSON(data []byte) error {
+	var text string
+	if json.Unmarshal(data, &text) == nil {
+		*c = Component{Text: text}
+		return nil
+	}
+
+	var list []Component
+	if json.Unmarshal(data, &list) == nil {
+		*c = Component{}
+
+		if len(list) > 0 {
+			*c = list[0]
+			c.Extra = append(c.Extra, list[1:]...)
+		}
+
+		return nil
+	}
+
+	//alias drops the custom unmarshaler to decode the object form
+	type alias Component
+	var object alias
+
+	if err := json.Unmarshal(data, &object); err != nil {
+		return err
+	}
+
+	*c = Component(object)
+	return nil
+}
+
+// Style the formatting applied to a run of text
+type Style struct {
+	Color         string
+	Bold          bool
+	Italic        bool
+	Underlined    bool
+	Strikethrough bool
+	Obfuscated    bool
+}
+
+// Segment a run of text sharing one style
+type Segment struct {
+	Text  string
+	Style Style
+}
+
+// Parse flattens a chat component json into styled segments, legacy § codes inside the text are honored
+func Parse(raw json.RawMessage) ([]Segment, error) {
+	component := &Component{}
+
+	if err := json.Unmarshal(raw, component); err != nil {
+		return nil, err
+	}
+
+	return flatten(component, Style{}), nil
+}
+
+// ParseLegacy splits a string formatted with legacy § codes into styled segments
+func ParseLegacy(s string) []Segment {
+	return parseLegacy(s, Style{})
+}
+
+func flatten(c *Component, parent Style) []Segment {
+	style := parent
+
+	if c.Color != "" {
+		style.Color = c.Color
+	}
+
+	inherit := func(value *bool, current bool) bool {
+		if value == nil {
+			return current
+		}
+
+		return *value
+	}
+
+	style.Bold = inherit(c.Bold, style.Bold)
+	style.Italic = inherit(c.Italic, style.Italic)
+	style.Underlined = inherit(c.Underlined, style.Underlined)
+	style.Strikethrough = inherit(c.Strikethrough, style.Strikethrough)
+	style.Obfuscated = inherit(c.Obfuscated, style.Obfuscated)
+
+	text := c.Text
+	if text == "" {
+		text = c.Translate
+	}
+
+	segments := parseLegacy(text, style)
+
+	for i := range c.Extra {
+		segments = append(segments, flatten(&c.Extra[i], style)...)
+	}
+
+	return segments
+}
+
+func parseLegacy(s string, base Style) []Segment {
+	var segments []Segment
+	var current strings.Builder
+	style := base
+
+	flush := func() {
+		if current.Len() > 0 {
+			segments = append(segments, Segment{Text: current.String(), Style: style})
+			current.Reset()
+		}
+	}
+
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '§' || i+1 >= len(runes) {
+			current.WriteRune(runes[i])
+			continue
+		}
+
+		code := legacyCode(runes[i+1])
+		i++
+		flush()
+
+		switch {
+		case code == 'r':
+			style = Style{}
+		case legacyColors[code] != "":
+			//a color code also clears any formatting before it
+			style = Style{Color: legacyColors[code]}
+		case code == 'k':
+			style.Obfuscated = true
+		case code == 'l':
+			style.Bold = true
+		case code == 'm':
+			style.Strikethrough = true
+		case code == 'n':
+			style.Underlined = true
+		case code == 'o':
+			style.Italic = true
+		}
+	}
+
+	flush()
+	return segments
+}
+
+func legacyCode(r rune) rune {
+	return []rune(strings.ToLower(string(r)))[0]
+}
+
+// legacyColors the color names of the § color codes
+var legacyColors = map[rune]string{
+	'0': "black",
+	'1': "dark_blue",
+	'2': "dark_green",
+	'3': "dark_aqua",
+	'4': "dark_red",
+	'5': "dark_purple",
+	'6': "gold",
+	'7': "gray",
+	'8': "dark_gray",
+	'9': "blue",
+	'a': "green",
+	'b': "aqua",
+	'c': "red",
+	'd': "light_purple",
+	'e': "yellow",
+	'f': "white",
+}
+
+// namedColors the rgb value of every named chat color
+var namedColors = map[string]string{
+	"black":        "#000000",
+	"dark_blue":    "#0000AA",
+	"dark_green":   "#00AA00",
+	"dark_aqua":    "#00AAAA",
+	"dark_red":     "#AA0000",
+	"dark_purple":  "#AA00AA",
+	"gold":         "#FFAA00",
+	"gray":         "#AAAAAA",
+	"dark_gray":    "#555555",
+	"blue":         "#5555FF",
+	"green":        "#55FF55",
+	"aqua":         "#55FFFF",
+	"red":          "#FF5555",
+	"light_purple": "#FF55FF",
+	"yellow":       "#FFFF55",
+	"white":        "#FFFFFF",
+}
+
+// ansiColors the closest 16 color terminal code of every named chat color
+var ansiColors = map[string]string{
+	"black":        "30",
+	"dark_blue":    "34",
+	"dark_green":   "32",
+	"dark_aqua":    "36",
+	"dark_red":     "31",
+	"dark_purple":  "35",
+	"gold":         "33",
+	"gray":         "37",
+	"dark_gray":    "90",
+	"blue":         "94",
+	"green":        "92",
+	"aqua":         "96",
+	"red":          "91",
+	"light_purple": "95",
+	"yellow":       "93",
+	"white":        "97",
+}
+
+// hexColor returns the rgb value of a named or #rrggbb color, or an empty string for unknown colors
+func hexColor(color string) string {
+	if named, ok := namedColors[color]; ok {
+		return named
+	}
+
+	var r, g, b uint8
+	if _, err := fmt.Sscanf(color, "#%02x%02x%02x", &r, &g, &b); err == nil && len(color) == 7 {
+		return strings.ToUpper(color)
+	}
+
+	return ""
+}
+
+// Plain renders the segments as unformatted text
+func Plain(segments []Segment) string {
+	var out strings.Builder
+
+	for _, segment := range segments {
+		out.WriteString(segment.Text)
+	}
+
+	return out.String()
+}
+
+// HTML renders the segments as escaped html spans with inline styles, line breaks become <br>
+func HTML(segments []Segment) string {
+	var out strings.Builder
+
+	for _, segment := range segments {
+		var styles []string
+
+		if color := hexColor(segment.Style.Color); color != "" {
+			styles = append(styles, fmt.Sprintf("color: %s", color))
+		}
+
+		if segment.Style.Bold {
+			styles = append(styles, "font-weight: bold")
+		}
+
+		if segment.Style.Italic {
+			styles = append(styles, "font-style: italic")
+		}
+
+		var decorations []string
+		if segment.Style.Underlined {
+			decorations = append(decorations, "underline")
+		}
+
+		if segment.Style.Strikethrough {
+			decorations = append(decorations, "line-through")
+		}
+
+		if len(decorations) > 0 {
+			styles = append(styles, fmt.Sprintf("text-decoration: %s", strings.Join(decorations, " ")))
+		}
+
+		text := strings.ReplaceAll(html.EscapeString(segment.Text), "\n", "<br>")
+
+		if len(styles) == 0 && !segment.Style.Obfuscated {
+			out.WriteString(text)
+			continue
+		}
+
+		out.WriteString("<span")
+
+		if segment.Style.Obfuscated {
+			out.WriteString(` class="obfuscated"`)
+		}
+
+		if len(styles) > 0 {
+			out.WriteString(fmt.Sprintf(` style="%s"`, strings.Join(styles, "; ")))
+		}
+
+		out.WriteString(">")
+		out.WriteString(text)
+		out.WriteString("</span>")
+	}
+
+	return out.String()
+}
+
+// ANSI renders the segments with terminal escape codes, hex colors use 24-bit color
+func ANSI(segments []Segment) string {
+	var out strings.Builder
+
+	for _, segment := range segments {
+		var codes []string
+
+		if code, ok := ansiColors[segment.Style.Color]; ok {
+			codes = append(codes, code)
+		} else if color := hexColor(segment.Style.Color); color != "" {
+			var r, g, b uint8
+			_, _ = fmt.Sscanf(color, "#%02x%02x%02x", &r, &g, &b)
+			codes = append(codes, fmt.Sprintf("38;2;%d;%d;%d", r, g, b))
+		}
+
+		if segment.Style.Bold {
+			codes = append(codes, "1")
+		}
+
+		if segment.Style.Italic {
+			codes = append(codes, "3")
+		}
+
+		if segment.Style.Underlined {
+			codes = append(codes, "4")
+		}
+
+		if segment.Style.Strikethrough {
+			codes = append(codes, "9")
+		}
+
+		if len(codes) == 0 {
+			out.WriteString(segment.Text)
+			continue
+		}
+
+		out.WriteString(fmt.Sprintf("\x1b[%sm%s\x1b[0m", strings.Join(codes, ";"), segment.Text))
+	}
+
+	return out.String()
+}
+
+// Format renders the segments as html, ansi or plain text
+func Format(segments []Segment, format string) (string, error) {
+	switch format {
+	case "html":
+		return HTML(segments), nil
+	case "ansi":
+		return ANSI(segments), nil
+	case "plain":
+		return Plain(segments), nil
+	default:
+		return "", fmt.Errorf("unknown motd format: %s", format)
+	}
+}
@@ -0,0 +1,52 @@
+package motd
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	raw := json.RawMessage(`{"text":"","extra":[{"text":"Hypixel ","color":"green","bold":true},"§cNetwork §r<3\n",{"text":"line two","color":"#12ab34"}]}`)
+
+	segments, err := Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"plain": "Hypixel Network <3\nline two",
+		"html":  `<span style="color: #55FF55; font-weight: bold">Hypixel </span><span style="color: #FF5555">Network </span>&lt;3<br><span style="color: #12AB34">line two</span>`,
+		"ansi":  "\x1b[92;1mHypixel \x1b[0m\x1b[91mNetwork \x1b[0m<3\n\x1b[38;2;18;171;52mline two\x1b[0m",
+	}
+
+	for format, want := range expected {
+		got, err := Format(segments, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got != want {
+			t.Errorf("%s: got %q, want %q", format, got, want)
+		}
+	}
+}
+
+func TestParseLegacy(t *testing.T) {
+	segments := ParseLegacy("§6§lGold §oitalic§aGreen")
+
+	if len(segments) != 3 {
+		t.Fatalf("expected 3 segments, got %d: %v", len(segments), segments)
+	}
+
+	if !segments[0].Style.Bold || segments[0].Style.Color != "gold" {
+		t.Errorf("unexpected first segment %+v", segments[0])
+	}
+
+	if !segments[1].Style.Italic || !segments[1].Style.Bold {
+		t.Errorf("formatting codes should stack: %+v", segments[1])
+	}
+
+	if segments[2].Style.Bold || segments[2].Style.Color != "green" {
+		t.Errorf("color codes should reset formatting: %+v", segments[2])
+	}
+}
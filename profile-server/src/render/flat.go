@@ -0,0 +1,141 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// canvas an output image addressed in skin pixel units
+type canvas struct {
+	Image *image.NRGBA
+	scale float64
+}
+
+// newCanvas creates a canvas of w by h units, scaled to the given output width in pixels
+func newCanvas(w float64, h float64, width int) *canvas {
+	scale := float64(width) / w
+	height := int(math.Round(h * scale))
+
+	return &canvas{
+		Image: image.NewNRGBA(image.Rect(0, 0, width, height)),
+		scale: scale,
+	}
+}
+
+// blit draws the src region of the skin into the unit rectangle at x, y using nearest neighbour sampling
+func (c *canvas) blit(src *image.NRGBA, r image.Rectangle, x float64, y float64, w float64, h float64) {
+	x0, y0 := int(math.Round(x*c.scale)), int(math.Round(y*c.scale))
+	x1, y1 := int(math.Round((x+w)*c.scale)), int(math.Round((y+h)*c.scale))
+
+	if x1 <= x0 || y1 <= y0 {
+		return
+	}
+
+	for py := y0; py < y1; py++ {
+		sy := r.Min.Y + (py-y0)*r.Dy()/(y1-y0)
+
+		for px := x0; px < x1; px++ {
+			sx := r.Min.X + (px-x0)*r.Dx()/(x1-x0)
+			blend(c.Image, px, py, src.NRGBAAt(sx, sy))
+		}
+	}
+}
+
+// blend composites src over the pixel of dst at x, y
+func blend(dst *image.NRGBA, x int, y int, src color.NRGBA) {
+	if !(image.Point{X: x, Y: y}.In(dst.Rect)) || src.A == 0 {
+		return
+	}
+
+	if src.A == 0xff {
+		dst.SetNRGBA(x, y, src)
+		return
+	}
+
+	d := dst.NRGBAAt(x, y)
+	sa := float64(src.A) / 0xff
+	da := float64(d.A) / 0xff
+	oa := sa + da*(1-sa)
+
+	mix := func(s uint8, d uint8) uint8 {
+		return uint8(math.Round((float64(s)*sa + float64(d)*da*(1-sa)) / oa))
+	}
+
+	dst.SetNRGBA(x, y, color.NRGBA{
+		R: mix(src.R, d.R),
+		G: mix(src.G, d.G),
+		B: mix(src.B, d.B),
+		A: uint8(math.Round(oa * 0xff)),
+	})
+}
+
+// Face renders the front of the head, width pixels wide
+func Face(s *Skin, width int, overlay bool) *image.NRGBA {
+	c := newCanvas(8, 8, width)
+
+	c.blit(s.Image, s.model.Head.rect(faceFront), 0, 0, 8, 8)
+
+	if overlay {
+		c.blit(s.Image, s.model.Hat.rect(faceFront), 0, 0, 8, 8)
+	}
+
+	return c.Image
+}
+
+// Head renders the front of the head with the hat layer inflated around it like in game, width pixels wide
+func Head(s *Skin, width int, overlay bool) *image.NRGBA {
+	c := newCanvas(9, 9, width)
+
+	c.blit(s.Image, s.model.Head.rect(faceFront), 0.5, 0.5, 8, 8)
+
+	if overlay {
+		c.blit(s.Image, s.model.Hat.rect(faceFront), 0, 0, 9, 9)
+	}
+
+	return c.Image
+}
+
+// Bust renders the front of the head, torso and upper arms, width pixels wide
+func Bust(s *Skin, width int, overlay bool) *image.NRGBA {
+	c := newCanvas(16, 16, width)
+	front(c, s, overlay)
+
+	return c.Image
+}
+
+// Body renders the front of the full player model, width pixels wide
+func Body(s *Skin, width int, overlay bool) *image.NRGBA {
+	c := newCanvas(16, 32, width)
+	front(c, s, overlay)
+
+	return c.Image
+}
+
+// front draws the front view of the player model onto a canvas 16 units wide, cropped to the canvas height
+func front(c *canvas, s *Skin, overlay bool) {
+	m := s.model
+	aw := float64(m.ArmWidth)
+
+	type placement struct {
+		base, overlay part
+		x, y, w, h    float64
+	}
+
+	placements := []placement{
+		{m.Head, m.Hat, 4, 0, 8, 8},
+		{m.Body, m.Jacket, 4, 8, 8, 12},
+		{m.RightArm, m.RightSleeve, 4 - aw, 8, aw, 12},
+		{m.LeftArm, m.LeftSleeve, 12, 8, aw, 12},
+		{m.RightLeg, m.RightPants, 4, 20, 4, 12},
+		{m.LeftLeg, m.LeftPants, 8, 20, 4, 12},
+	}
+
+	for _, p := range placements {
+		c.blit(s.Image, p.base.rect(faceFront), p.x, p.y, p.w, p.h)
+
+		if overlay {
+			c.blit(s.Image, p.overlay.rect(faceFront), p.x, p.y, p.w, p.h)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+var partColors = map[string]color.NRGBA{
+	"head":     {R: 200, A: 0xff},
+	"body":     {G: 200, A: 0xff},
+	"rightArm": {B: 200, A: 0xff},
+	"leftArm":  {R: 200, G: 200, A: 0xff},
+	"rightLeg": {G: 200, B: 200, A: 0xff},
+	"leftLeg":  {R: 200, B: 200, A: 0xff},
+}
+
+// assertPixels checks the color of the image at every sampled point
+func assertPixels(t *testing.T, name string, img *image.NRGBA, samples map[image.Point]color.NRGBA) {
+	t.Helper()
+
+	for p, want := range samples {
+		if got := img.NRGBAAt(p.X, p.Y); got != want {
+			t.Errorf("%s at %v = %v, want %v", name, p, got, want)
+		}
+	}
+}
+
+func TestFace(t *testing.T) {
+	skin := newPartSkin(t, false, partColors)
+
+	//a single hat pixel in the top left corner of the face
+	fill(skin.Image, image.Rect(40, 8, 41, 9), blue)
+
+	face := Face(skin, 64, false)
+	if face.Bounds() != image.Rect(0, 0, 64, 64) {
+		t.Fatalf("Face bounds = %v", face.Bounds())
+	}
+
+	assertPixels(t, "Face", face, map[image.Point]color.NRGBA{
+		{0, 0}:   red,
+		{63, 63}: red,
+	})
+
+	//every skin pixel is scaled up to an 8x8 block
+	assertPixels(t, "Face with overlay", Face(skin, 64, true), map[image.Point]color.NRGBA{
+		{0, 0}: blue,
+		{7, 7}: blue,
+		{8, 0}: red,
+		{0, 8}: red,
+	})
+}
+
+func TestHead(t *testing.T) {
+	skin := newPartSkin(t, false, partColors)
+	fill(skin.Image, image.Rect(40, 8, 41, 9), blue)
+
+	//the face is inset half a skin pixel, 4 output pixels at this size, leaving room for the hat
+	head := Head(skin, 72, false)
+	if head.Bounds() != image.Rect(0, 0, 72, 72) {
+		t.Fatalf("Head bounds = %v", head.Bounds())
+	}
+
+	assertPixels(t, "Head", head, map[image.Point]color.NRGBA{
+		{3, 3}:   {},
+		{4, 4}:   red,
+		{67, 67}: red,
+		{68, 68}: {},
+	})
+
+	//the inflated hat covers the inset, a hat pixel is 9 output pixels wide
+	assertPixels(t, "Head with overlay", Head(skin, 72, true), map[image.Point]color.NRGBA{
+		{0, 0}:   blue,
+		{8, 8}:   blue,
+		{9, 9}:   red,
+		{71, 71}: {},
+	})
+}
+
+func TestBody(t *testing.T) {
+	skin := newPartSkin(t, false, partColors)
+
+	body := Body(skin, 16, false)
+	if body.Bounds() != image.Rect(0, 0, 16, 32) {
+		t.Fatalf("Body bounds = %v", body.Bounds())
+	}
+
+	//the right side of the player is on the left of the image
+	regions := map[string]image.Rectangle{
+		"head":     image.Rect(4, 0, 12, 8),
+		"body":     image.Rect(4, 8, 12, 20),
+		"rightArm": image.Rect(0, 8, 4, 20),
+		"leftArm":  image.Rect(12, 8, 16, 20),
+		"rightLeg": image.Rect(4, 20, 8, 32),
+		"leftLeg":  image.Rect(8, 20, 12, 32),
+	}
+
+	for name, r := range regions {
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				if got := body.NRGBAAt(x, y); got != partColors[name] {
+					t.Fatalf("Body %s at %d,%d = %v, want %v", name, x, y, got, partColors[name])
+				}
+			}
+		}
+	}
+
+	assertPixels(t, "Body", body, map[image.Point]color.NRGBA{
+		{0, 0}:   {},
+		{0, 20}:  {},
+		{15, 31}: {},
+	})
+
+	//the bust is the top half of the body
+	bust := Bust(skin, 16, false)
+	if bust.Bounds() != image.Rect(0, 0, 16, 16) {
+		t.Fatalf("Bust bounds = %v", bust.Bounds())
+	}
+
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			if bust.NRGBAAt(x, y) != body.NRGBAAt(x, y) {
+				t.Fatalf("Bust at %d,%d = %v, want %v", x, y, bust.NRGBAAt(x, y), body.NRGBAAt(x, y))
+			}
+		}
+	}
+
+	//slim arms are a pixel narrower and stay attached to the body
+	slim := Body(newPartSkin(t, true, partColors), 16, false)
+
+	assertPixels(t, "Body slim", slim, map[image.Point]color.NRGBA{
+		{0, 10}:  {},
+		{1, 10}:  partColors["rightArm"],
+		{14, 10}: partColors["leftArm"],
+		{15, 10}: {},
+	})
+}
+
+func TestOverlay(t *testing.T) {
+	skin := newPartSkin(t, false, partColors)
+	m := skin.model
+
+	//a half transparent jacket is blended over the body, transparent pixels leave it untouched
+	jacket := m.Jacket.rect(faceFront)
+	fill(skin.Image, image.Rect(jacket.Min.X, jacket.Min.Y, jacket.Min.X+1, jacket.Min.Y+1), color.NRGBA{R: 200, A: 0x80})
+
+	sleeve := m.RightSleeve.rect(faceFront)
+	fill(skin.Image, sleeve, blue)
+
+	assertPixels(t, "Body with overlay", Body(skin, 16, true), map[image.Point]color.NRGBA{
+		{4, 8}:  {R: 100, G: 100, A: 0xff},
+		{5, 8}:  partColors["body"],
+		{0, 8}:  blue,
+		{12, 8}: partColors["leftArm"],
+	})
+
+	//without the overlay the base layer shows
+	assertPixels(t, "Body", Body(skin, 16, false), map[image.Point]color.NRGBA{
+		{4, 8}: partColors["body"],
+		{0, 8}: partColors["rightArm"],
+	})
+}
@@ -0,0 +1,334 @@
+package render
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"sort"
+)
+
+// isoPitch the camera angle above the horizon giving a true isometric projection
+var isoPitch = math.Atan(1 / math.Sqrt2)
+
+type vec3 struct {
+	X, Y, Z float64
+}
+
+func (a vec3) add(b vec3) vec3 {
+	return vec3{a.X + b.X, a.Y + b.Y, a.Z + b.Z}
+}
+
+func (a vec3) scale(s float64) vec3 {
+	return vec3{a.X * s, a.Y * s, a.Z * s}
+}
+
+// box a textured cuboid placed in model space, one unit per skin pixel with y pointing up and the player facing +z
+type box struct {
+	part     part
+	texture  *image.NRGBA
+	min, max vec3
+	// reversed the texture wraps the box facing -z, like the cape hanging behind the player
+	reversed bool
+}
+
+// inflate grows the box by d units in every direction, used to offset the overlay layer from the base layer
+func (b box) inflate(d float64) box {
+	b.min = b.min.add(vec3{-d, -d, -d})
+	b.max = b.max.add(vec3{d, d, d})
+
+	return b
+}
+
+// side the placement of a face of a box in model space: the texture u axis runs from origin along u, v along v
+type side struct {
+	face   face
+	origin vec3
+	u, v   vec3
+	normal vec3
+	shade  float64
+}
+
+func (b box) sides() []side {
+	x0, y0, z0 := b.min.X, b.min.Y, b.min.Z
+	x1, y1, z1 := b.max.X, b.max.Y, b.max.Z
+	w, h, d := x1-x0, y1-y0, z1-z0
+
+	sides := []side{
+		{faceFront, vec3{x0, y1, z1}, vec3{w, 0, 0}, vec3{0, -h, 0}, vec3{0, 0, 1}, 0.8},
+		{faceBack, vec3{x1, y1, z0}, vec3{-w, 0, 0}, vec3{0, -h, 0}, vec3{0, 0, -1}, 0.8},
+		{faceRight, vec3{x0, y1, z0}, vec3{0, 0, d}, vec3{0, -h, 0}, vec3{-1, 0, 0}, 0.65},
+		{faceLeft, vec3{x1, y1, z1}, vec3{0, 0, -d}, vec3{0, -h, 0}, vec3{1, 0, 0}, 0.65},
+		{faceTop, vec3{x0, y1, z0}, vec3{w, 0, 0}, vec3{0, 0, d}, vec3{0, 1, 0}, 1},
+		{faceBottom, vec3{x0, y0, z1}, vec3{w, 0, 0}, vec3{0, 0, -d}, vec3{0, -1, 0}, 0.5},
+	}
+
+	if b.reversed {
+		//turning the box around swaps which texture face ends up on which side
+		for i := range sides {
+			switch sides[i].face {
+			case faceFront:
+				sides[i].face = faceBack
+			case faceBack:
+				sides[i].face = faceFront
+			case faceRight:
+				sides[i].face = faceLeft
+			case faceLeft:
+				sides[i].face = faceRight
+			}
+		}
+	}
+
+	return sides
+}
+
+// camera an orthographic camera orbiting the model
+type camera struct {
+	sinYaw, cosYaw     float64
+	sinPitch, cosPitch float64
+}
+
+func newCamera(yaw float64, pitch float64) camera {
+	return camera{
+		sinYaw:   math.Sin(yaw),
+		cosYaw:   math.Cos(yaw),
+		sinPitch: math.Sin(pitch),
+		cosPitch: math.Cos(pitch),
+	}
+}
+
+// project returns the screen position of p, with y pointing down, and its depth where larger is closer to the camera
+func (c camera) project(p vec3) (float64, float64, float64) {
+	x := p.X*c.cosYaw - p.Z*c.sinYaw
+	z := p.X*c.sinYaw + p.Z*c.cosYaw
+	y := p.Y*c.cosPitch - z*c.sinPitch
+	depth := p.Y*c.sinPitch + z*c.cosPitch
+
+	return x, -y, depth
+}
+
+// facing reports if a face with the given normal is turned towards the camera
+func (c camera) facing(normal vec3) bool {
+	_, _, depth := c.project(normal)
+	return depth > 1e-9
+}
+
+// quad a single texel of a face projected onto the screen
+type quad struct {
+	points [4][2]float64
+	depth  float64
+	color  color.NRGBA
+}
+
+// IsometricOptions options for the Isometric render
+type IsometricOptions struct {
+	// Width of the output image in pixels
+	Width int
+	// Overlay renders the outer skin layers
+	Overlay bool
+	// Cape optional cape texture hanging behind the player
+	Cape *image.NRGBA
+	// Back views the player from behind instead of the front
+	Back bool
+}
+
+// NewCape normalizes a decoded cape texture to the 64x32 layout, legacy 22x17 capes are padded
+func NewCape(src image.Image) (*image.NRGBA, error) {
+	bounds := src.Bounds()
+
+	if !(bounds.Dx() == 64 && bounds.Dy() == 32) && !(bounds.Dx() == 22 && bounds.Dy() == 17) {
+		return nil, fmt.Errorf("unsupported cape dimensions %dx%d", bounds.Dx(), bounds.Dy())
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, 64, 32))
+	draw.Draw(img, image.Rect(0, 0, bounds.Dx(), bounds.Dy()), src, bounds.Min, draw.Src)
+
+	return img, nil
+}
+
+// Isometric software renders the player model in an isometric projection
+func Isometric(s *Skin, options IsometricOptions) *image.NRGBA {
+	m := s.model
+	aw := float64(m.ArmWidth)
+
+	place := func(p part, min vec3, max vec3) box {
+		return box{part: p, texture: s.Image, min: min, max: max}
+	}
+
+	type layered struct {
+		base, overlay box
+		inflate       float64
+	}
+
+	layers := []layered{
+		{place(m.Head, vec3{-4, 24, -4}, vec3{4, 32, 4}), place(m.Hat, vec3{-4, 24, -4}, vec3{4, 32, 4}), 0.5},
+		{place(m.Body, vec3{-4, 12, -2}, vec3{4, 24, 2}), place(m.Jacket, vec3{-4, 12, -2}, vec3{4, 24, 2}), 0.25},
+		{place(m.RightArm, vec3{-4 - aw, 12, -2}, vec3{-4, 24, 2}), place(m.RightSleeve, vec3{-4 - aw, 12, -2}, vec3{-4, 24, 2}), 0.25},
+		{place(m.LeftArm, vec3{4, 12, -2}, vec3{4 + aw, 24, 2}), place(m.LeftSleeve, vec3{4, 12, -2}, vec3{4 + aw, 24, 2}), 0.25},
+		{place(m.RightLeg, vec3{-4, 0, -2}, vec3{0, 12, 2}), place(m.RightPants, vec3{-4, 0, -2}, vec3{0, 12, 2}), 0.25},
+		{place(m.LeftLeg, vec3{0, 0, -2}, vec3{4, 12, 2}), place(m.LeftPants, vec3{0, 0, -2}, vec3{4, 12, 2}), 0.25},
+	}
+
+	var boxes []box
+	for _, l := range layers {
+		boxes = append(boxes, l.base)
+
+		if options.Overlay {
+			boxes = append(boxes, l.overlay.inflate(l.inflate))
+		}
+	}
+
+	if options.Cape != nil {
+		boxes = append(boxes, box{
+			part:     part{0, 0, 10, 16, 1},
+			texture:  options.Cape,
+			min:      vec3{-5, 8, -3.5},
+			max:      vec3{5, 24, -2.5},
+			reversed: true,
+		})
+	}
+
+	//look at the front and the right side of the player, or the back and the left side
+	yaw := -math.Pi / 4
+	if options.Back {
+		yaw += math.Pi
+	}
+
+	cam := newCamera(yaw, isoPitch)
+
+	//split every visible face into its texels
+	var quads []quad
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+
+	for _, b := range boxes {
+		for _, sd := range b.sides() {
+			r := b.part.rect(sd.face)
+
+			for _, corner := range []vec3{sd.origin, sd.origin.add(sd.u), sd.origin.add(sd.v), sd.origin.add(sd.u).add(sd.v)} {
+				x, y, _ := cam.project(corner)
+				minX, minY = math.Min(minX, x), math.Min(minY, y)
+				maxX, maxY = math.Max(maxX, x), math.Max(maxY, y)
+			}
+
+			if !cam.facing(sd.normal) {
+				continue
+			}
+
+			du := sd.u.scale(1 / float64(r.Dx()))
+			dv := sd.v.scale(1 / float64(r.Dy()))
+
+			for ty := 0; ty < r.Dy(); ty++ {
+				for tx := 0; tx < r.Dx(); tx++ {
+					c := b.texture.NRGBAAt(r.Min.X+tx, r.Min.Y+ty)
+
+					if c.A == 0 {
+						continue
+					}
+
+					origin := sd.origin.add(du.scale(float64(tx))).add(dv.scale(float64(ty)))
+					corners := [4]vec3{origin, origin.add(du), origin.add(du).add(dv), origin.add(dv)}
+
+					q := quad{color: shade(c, sd.shade)}
+					for i, corner := range corners {
+						x, y, _ := cam.project(corner)
+						q.points[i] = [2]float64{x, y}
+					}
+
+					_, _, q.depth = cam.project(origin.add(du.scale(0.5)).add(dv.scale(0.5)))
+					quads = append(quads, q)
+				}
+			}
+		}
+	}
+
+	//fit the model into the requested width with a small margin
+	margin := 1.0
+	scale := float64(options.Width) / (maxX - minX + 2*margin)
+	height := int(math.Ceil((maxY - minY + 2*margin) * scale))
+	img := image.NewNRGBA(image.Rect(0, 0, options.Width, height))
+
+	//painter's algorithm, draw the texels furthest from the camera first
+	sort.SliceStable(quads, func(i, j int) bool {
+		return quads[i].depth < quads[j].depth
+	})
+
+	for _, q := range quads {
+		for i := range q.points {
+			q.points[i][0] = (q.points[i][0] - minX + margin) * scale
+			q.points[i][1] = (q.points[i][1] - minY + margin) * scale
+		}
+
+		fillQuad(img, q)
+	}
+
+	return img
+}
+
+// shade darkens the color to give the faces of the model some depth
+func shade(c color.NRGBA, s float64) color.NRGBA {
+	return color.NRGBA{
+		R: uint8(float64(c.R) * s),
+		G: uint8(float64(c.G) * s),
+		B: uint8(float64(c.B) * s),
+		A: c.A,
+	}
+}
+
+// fillQuad rasterizes a convex quad, slightly overdrawing its edges so adjacent texels leave no seams
+func fillQuad(img *image.NRGBA, q quad) {
+	const tolerance = 0.25
+
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+
+	for _, p := range q.points {
+		minX, minY = math.Min(minX, p[0]), math.Min(minY, p[1])
+		maxX, maxY = math.Max(maxX, p[0]), math.Max(maxY, p[1])
+	}
+
+	//signed area tells the winding order of the points
+	area := 0.0
+	for i := range q.points {
+		a, b := q.points[i], q.points[(i+1)%4]
+		area += a[0]*b[1] - b[0]*a[1]
+	}
+
+	if math.Abs(area) < 1e-9 {
+		return
+	}
+
+	winding := 1.0
+	if area < 0 {
+		winding = -1
+	}
+
+	for py := int(math.Floor(minY)); py <= int(math.Ceil(maxY)); py++ {
+		for px := int(math.Floor(minX)); px <= int(math.Ceil(maxX)); px++ {
+			x, y := float64(px)+0.5, float64(py)+0.5
+			inside := true
+
+			for i := range q.points {
+				a, b := q.points[i], q.points[(i+1)%4]
+				ex, ey := b[0]-a[0], b[1]-a[1]
+				length := math.Hypot(ex, ey)
+
+				if length == 0 {
+					continue
+				}
+
+				//signed distance of the pixel center to the edge, positive on the inside
+				distance := winding * (ex*(y-a[1]) - ey*(x-a[0])) / length
+				if distance < -tolerance {
+					inside = false
+					break
+				}
+			}
+
+			if inside {
+				blend(img, px, py, q.color)
+			}
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+var faceColors = map[face]color.NRGBA{
+	faceTop:    {R: 200, A: 0xff},
+	faceBottom: {R: 200, B: 200, A: 0xff},
+	faceRight:  {B: 200, A: 0xff},
+	faceFront:  {G: 200, A: 0xff},
+	faceLeft:   {R: 200, G: 200, A: 0xff},
+	faceBack:   {G: 200, B: 200, A: 0xff},
+}
+
+var capeColor = color.NRGBA{R: 100, G: 50, B: 200, A: 0xff}
+
+// newFaceSkin creates a 64x64 skin painting every face of the base parts by the side it is on
+func newFaceSkin(t *testing.T) *Skin {
+	img := image.NewNRGBA(image.Rect(0, 0, 64, 64))
+	m := newModel(false)
+
+	for _, p := range []part{m.Head, m.Body, m.RightArm, m.LeftArm, m.RightLeg, m.LeftLeg} {
+		for f, c := range faceColors {
+			fill(img, p.rect(f), c)
+		}
+	}
+
+	skin, err := NewSkin(img, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return skin
+}
+
+// newTestCape creates a cape texture with its front, the side facing away from the player, painted capeColor
+func newTestCape() *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 64, 32))
+	fill(img, part{0, 0, 10, 16, 1}.rect(faceFront), capeColor)
+
+	return img
+}
+
+// isoPixel the output pixel of a point of the classic model rendered width pixels wide.
+// Seen from either side the model spans 10/√2 units to the left and right of its center, the arms sticking out furthest,
+// and from the far top corner of the head at 32 units high down to the near bottom corner of a leg
+func isoPixel(p vec3, back bool, width int) image.Point {
+	yaw := -math.Pi / 4
+	if back {
+		yaw += math.Pi
+	}
+
+	cam := newCamera(yaw, isoPitch)
+	sinPitch, cosPitch := math.Sin(isoPitch), math.Cos(isoPitch)
+
+	minX := -10 / math.Sqrt2
+	minY := -(32*cosPitch + 8/math.Sqrt2*sinPitch)
+	scale := float64(width) / (20/math.Sqrt2 + 2)
+
+	x, y, _ := cam.project(p)
+	return image.Pt(int((x-minX+1)*scale), int((y-minY+1)*scale))
+}
+
+// shaded the color of a face lit like the renderer does
+func shaded(f face, s float64) color.NRGBA {
+	return shade(faceColors[f], s)
+}
+
+// countColor counts the pixels of the image of exactly the color c
+func countColor(img *image.NRGBA, c color.NRGBA) int {
+	n := 0
+
+	for y := img.Rect.Min.Y; y < img.Rect.Max.Y; y++ {
+		for x := img.Rect.Min.X; x < img.Rect.Max.X; x++ {
+			if img.NRGBAAt(x, y) == c {
+				n++
+			}
+		}
+	}
+
+	return n
+}
+
+func TestIsometric(t *testing.T) {
+	skin := newFaceSkin(t)
+	width := 160
+
+	front := Isometric(skin, IsometricOptions{Width: width})
+
+	//an isometric projection keeps the model 31.8 units tall next to 14.1 units wide
+	sinPitch, cosPitch := math.Sin(isoPitch), math.Cos(isoPitch)
+	height := 32*cosPitch + 14/math.Sqrt2*sinPitch + 2
+	scale := float64(width) / (20/math.Sqrt2 + 2)
+
+	if want := image.Rect(0, 0, width, int(math.Ceil(height*scale))); front.Bounds() != want {
+		t.Fatalf("Isometric bounds = %v, want %v", front.Bounds(), want)
+	}
+
+	//the front, right side and top of the player face the camera
+	assertPixels(t, "Isometric front", front, map[image.Point]color.NRGBA{
+		isoPixel(vec3{0, 32, 0}, false, width):  shaded(faceTop, 1),
+		isoPixel(vec3{0, 28, 4}, false, width):  shaded(faceFront, 0.8),
+		isoPixel(vec3{-4, 28, 0}, false, width): shaded(faceRight, 0.65),
+		isoPixel(vec3{0, 18, 2}, false, width):  shaded(faceFront, 0.8),
+		isoPixel(vec3{-8, 18, 0}, false, width): shaded(faceRight, 0.65),
+	})
+
+	for _, f := range []face{faceBack, faceLeft, faceBottom} {
+		if n := countColor(front, shaded(f, 0.8)) + countColor(front, shaded(f, 0.65)) + countColor(front, shaded(f, 0.5)); n > 0 {
+			t.Errorf("Isometric front shows %d pixels of hidden face %d", n, f)
+		}
+	}
+
+	//from behind the back, left side and top face the camera
+	back := Isometric(skin, IsometricOptions{Width: width, Back: true})
+
+	assertPixels(t, "Isometric back", back, map[image.Point]color.NRGBA{
+		isoPixel(vec3{0, 32, 0}, true, width):  shaded(faceTop, 1),
+		isoPixel(vec3{0, 28, -4}, true, width): shaded(faceBack, 0.8),
+		isoPixel(vec3{4, 28, 0}, true, width):  shaded(faceLeft, 0.65),
+		isoPixel(vec3{0, 18, -2}, true, width): shaded(faceBack, 0.8),
+	})
+
+	if n := countColor(back, shaded(faceFront, 0.8)); n > 0 {
+		t.Errorf("Isometric back shows %d pixels of the front", n)
+	}
+}
+
+func TestIsometricCape(t *testing.T) {
+	skin := newFaceSkin(t)
+	width := 160
+	cape := newTestCape()
+
+	//the cape hangs behind the back, covering it when seen from behind
+	back := Isometric(skin, IsometricOptions{Width: width, Back: true, Cape: cape})
+	capePixel := isoPixel(vec3{0, 18, -3.5}, true, width)
+
+	assertPixels(t, "Isometric back with cape", back, map[image.Point]color.NRGBA{
+		capePixel:                              shade(capeColor, 0.8),
+		isoPixel(vec3{0, 32, 0}, true, width):  shaded(faceTop, 1),
+		isoPixel(vec3{0, 28, -4}, true, width): shaded(faceBack, 0.8),
+	})
+
+	//without it the body shows through
+	if got := Isometric(skin, IsometricOptions{Width: width, Back: true}).NRGBAAt(capePixel.X, capePixel.Y); got == shade(capeColor, 0.8) {
+		t.Errorf("Isometric back without cape at %v = %v, want the body", capePixel, got)
+	}
+
+	//the cape stays hidden behind the player seen from the front, without changing the framing
+	front := Isometric(skin, IsometricOptions{Width: width, Cape: cape})
+	plain := Isometric(skin, IsometricOptions{Width: width})
+
+	if front.Bounds() != plain.Bounds() || back.Bounds() != plain.Bounds() {
+		t.Errorf("Isometric with cape bounds = %v %v, want %v", front.Bounds(), back.Bounds(), plain.Bounds())
+	}
+
+	assertPixels(t, "Isometric front with cape", front, map[image.Point]color.NRGBA{
+		isoPixel(vec3{0, 18, 2}, false, width): shaded(faceFront, 0.8),
+	})
+}
@@ -0,0 +1,170 @@
+package render
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+)
+
+// face one side of a cuboid in the skin texture layout
+type face int
+
+const (
+	faceTop face = iota
+	faceBottom
+	faceRight
+	faceFront
+	faceLeft
+	faceBack
+)
+
+// part a cuboid of the player model, described by its position in the skin texture and its dimensions
+type part struct {
+	u, v    int
+	w, h, d int
+}
+
+// rect returns the region of the skin texture holding the given face of the part
+func (p part) rect(f face) image.Rectangle {
+	var x, y, w, h int
+
+	switch f {
+	case faceTop:
+		x, y, w, h = p.u+p.d, p.v, p.w, p.d
+	case faceBottom:
+		x, y, w, h = p.u+p.d+p.w, p.v, p.w, p.d
+	case faceRight:
+		x, y, w, h = p.u, p.v+p.d, p.d, p.h
+	case faceFront:
+		x, y, w, h = p.u+p.d, p.v+p.d, p.w, p.h
+	case faceLeft:
+		x, y, w, h = p.u+p.d+p.w, p.v+p.d, p.d, p.h
+	case faceBack:
+		x, y, w, h = p.u+2*p.d+p.w, p.v+p.d, p.w, p.h
+	}
+
+	return image.Rect(x, y, x+w, y+h)
+}
+
+// model the parts of a player model, each with its base and overlay layer
+type model struct {
+	Head, Hat             part
+	Body, Jacket          part
+	RightArm, RightSleeve part
+	LeftArm, LeftSleeve   part
+	RightLeg, RightPants  part
+	LeftLeg, LeftPants    part
+	ArmWidth              int
+}
+
+func newModel(slim bool) model {
+	armWidth := 4
+	if slim {
+		armWidth = 3
+	}
+
+	return model{
+		Head:        part{0, 0, 8, 8, 8},
+		Hat:         part{32, 0, 8, 8, 8},
+		Body:        part{16, 16, 8, 12, 4},
+		Jacket:      part{16, 32, 8, 12, 4},
+		RightArm:    part{40, 16, armWidth, 12, 4},
+		RightSleeve: part{40, 32, armWidth, 12, 4},
+		LeftArm:     part{32, 48, armWidth, 12, 4},
+		LeftSleeve:  part{48, 48, armWidth, 12, 4},
+		RightLeg:    part{0, 16, 4, 12, 4},
+		RightPants:  part{0, 32, 4, 12, 4},
+		LeftLeg:     part{16, 48, 4, 12, 4},
+		LeftPants:   part{0, 48, 4, 12, 4},
+		ArmWidth:    armWidth,
+	}
+}
+
+// Skin a player skin normalized to the 64x64 texture layout
+type Skin struct {
+	Image *image.NRGBA
+	Slim  bool
+	model model
+}
+
+// NewSkin normalizes a decoded skin texture, legacy 64x32 skins are converted to the 64x64 layout
+func NewSkin(src image.Image, slim bool) (*Skin, error) {
+	bounds := src.Bounds()
+
+	if bounds.Dx() != 64 || (bounds.Dy() != 64 && bounds.Dy() != 32) {
+		return nil, fmt.Errorf("unsupported skin dimensions %dx%d", bounds.Dx(), bounds.Dy())
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, 64, 64))
+	draw.Draw(img, image.Rect(0, 0, 64, bounds.Dy()), src, bounds.Min, draw.Src)
+
+	skin := &Skin{
+		Image: img,
+		Slim:  slim,
+		model: newModel(slim),
+	}
+
+	if bounds.Dy() == 32 {
+		//legacy skins only carry the right limbs, the game mirrors them onto the left side
+		legacy := newModel(false)
+		mirrorPart(img, legacy.RightLeg, legacy.LeftLeg)
+		mirrorPart(img, legacy.RightArm, legacy.LeftArm)
+
+		//legacy hats were commonly painted fully opaque, the game hides them in that case
+		hideOpaque(img, image.Rect(32, 0, 64, 16))
+	}
+
+	//the base layer is always rendered opaque by the game
+	setOpaque(img, image.Rect(0, 0, 32, 16))
+	setOpaque(img, image.Rect(0, 16, 64, 32))
+	setOpaque(img, image.Rect(16, 48, 48, 64))
+
+	return skin, nil
+}
+
+// mirrorPart copies the faces of src onto dst flipped horizontally, swapping the left and right sides
+func mirrorPart(img *image.NRGBA, src part, dst part) {
+	swapped := map[face]face{
+		faceTop:    faceTop,
+		faceBottom: faceBottom,
+		faceRight:  faceLeft,
+		faceFront:  faceFront,
+		faceLeft:   faceRight,
+		faceBack:   faceBack,
+	}
+
+	for from, to := range swapped {
+		srcRect := src.rect(from)
+		dstRect := dst.rect(to)
+
+		for y := 0; y < srcRect.Dy(); y++ {
+			for x := 0; x < srcRect.Dx(); x++ {
+				img.SetNRGBA(dstRect.Max.X-1-x, dstRect.Min.Y+y, img.NRGBAAt(srcRect.Min.X+x, srcRect.Min.Y+y))
+			}
+		}
+	}
+}
+
+// setOpaque forces every pixel of the region to full alpha
+func setOpaque(img *image.NRGBA, r image.Rectangle) {
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			c := img.NRGBAAt(x, y)
+			c.A = 0xff
+			img.SetNRGBA(x, y, c)
+		}
+	}
+}
+
+// hideOpaque clears the region if it has no transparent pixels at all
+func hideOpaque(img *image.NRGBA, r image.Rectangle) {
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			if img.NRGBAAt(x, y).A < 0x80 {
+				return
+			}
+		}
+	}
+
+	draw.Draw(img, r, image.Transparent, image.Point{}, draw.Src)
+}
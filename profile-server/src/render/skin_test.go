@@ -0,0 +1,140 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+var (
+	red   = color.NRGBA{R: 200, A: 0xff}
+	green = color.NRGBA{G: 200, A: 0xff}
+	blue  = color.NRGBA{B: 200, A: 0xff}
+)
+
+// fill paints the region of the image a single color
+func fill(img *image.NRGBA, r image.Rectangle, c color.NRGBA) {
+	draw.Draw(img, r, image.NewUniform(c), image.Point{}, draw.Src)
+}
+
+// newPartSkin creates a 64x64 skin painting the front of every base part its own color, the overlay layer left transparent
+func newPartSkin(t *testing.T, slim bool, colors map[string]color.NRGBA) *Skin {
+	img := image.NewNRGBA(image.Rect(0, 0, 64, 64))
+	m := newModel(slim)
+
+	parts := map[string]part{
+		"head":     m.Head,
+		"body":     m.Body,
+		"rightArm": m.RightArm,
+		"leftArm":  m.LeftArm,
+		"rightLeg": m.RightLeg,
+		"leftLeg":  m.LeftLeg,
+	}
+
+	for name, p := range parts {
+		fill(img, p.rect(faceFront), colors[name])
+	}
+
+	skin, err := NewSkin(img, slim)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return skin
+}
+
+func TestNewSkin(t *testing.T) {
+	for _, size := range []image.Point{{64, 16}, {32, 32}, {64, 128}} {
+		if _, err := NewSkin(image.NewNRGBA(image.Rect(0, 0, size.X, size.Y)), false); err == nil {
+			t.Errorf("NewSkin %v did not fail", size)
+		}
+	}
+
+	//the base layer is opaque even where the texture is not, the overlay keeps its transparency
+	skin, err := NewSkin(image.NewNRGBA(image.Rect(0, 0, 64, 64)), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if a := skin.Image.NRGBAAt(8, 8).A; a != 0xff {
+		t.Errorf("head alpha = %d, want opaque", a)
+	}
+
+	if a := skin.Image.NRGBAAt(40, 8).A; a != 0 {
+		t.Errorf("hat alpha = %d, want transparent", a)
+	}
+}
+
+func TestNewSkinLegacy(t *testing.T) {
+	legacy := image.NewNRGBA(image.Rect(0, 0, 64, 32))
+	m := newModel(false)
+
+	//every column of the right limbs a different color, so a mirrored copy can be told apart from a plain one
+	for _, p := range []part{m.RightLeg, m.RightArm} {
+		for _, f := range []face{faceTop, faceBottom, faceRight, faceFront, faceLeft, faceBack} {
+			r := p.rect(f)
+
+			for x := r.Min.X; x < r.Max.X; x++ {
+				fill(legacy, image.Rect(x, r.Min.Y, x+1, r.Max.Y), color.NRGBA{R: uint8(x * 4), G: uint8(f) * 40, B: 100, A: 0xff})
+			}
+		}
+	}
+
+	//an opaque legacy hat is hidden like in game
+	fill(legacy, image.Rect(32, 0, 64, 16), blue)
+
+	skin, err := NewSkin(legacy, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if skin.Image.Bounds() != image.Rect(0, 0, 64, 64) {
+		t.Fatalf("legacy skin bounds = %v, want 64x64", skin.Image.Bounds())
+	}
+
+	//the left limbs are the right limbs flipped, with the right and left faces swapped
+	mirrored := map[face]face{
+		faceTop:    faceTop,
+		faceBottom: faceBottom,
+		faceRight:  faceLeft,
+		faceFront:  faceFront,
+		faceLeft:   faceRight,
+		faceBack:   faceBack,
+	}
+
+	pairs := [][2]part{{m.RightLeg, m.LeftLeg}, {m.RightArm, m.LeftArm}}
+
+	for _, pair := range pairs {
+		for from, to := range mirrored {
+			src, dst := pair[0].rect(from), pair[1].rect(to)
+
+			for y := 0; y < src.Dy(); y++ {
+				for x := 0; x < src.Dx(); x++ {
+					want := skin.Image.NRGBAAt(src.Min.X+x, src.Min.Y+y)
+					got := skin.Image.NRGBAAt(dst.Max.X-1-x, dst.Min.Y+y)
+
+					if got != want {
+						t.Fatalf("face %d of %v at %d,%d = %v, want %v", to, pair[1], x, y, got, want)
+					}
+				}
+			}
+		}
+	}
+
+	if a := skin.Image.NRGBAAt(40, 8).A; a != 0 {
+		t.Errorf("opaque legacy hat alpha = %d, want hidden", a)
+	}
+
+	//a hat with transparent pixels is kept
+	fill(legacy, image.Rect(32, 0, 40, 8), color.NRGBA{})
+
+	skin, err = NewSkin(legacy, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c := skin.Image.NRGBAAt(40, 8); c != blue {
+		t.Errorf("legacy hat = %v, want %v", c, blue)
+	}
+}
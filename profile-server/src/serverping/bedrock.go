@@ -0,0 +1,161 @@
+package serverping
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"bed.gg/minecraft-api/v2/src/motd"
+)
+
+// DefaultBedrockPort default port of bedrock edition servers
+const DefaultBedrockPort = 19132
+
+const (
+	idUnconnectedPing = 0x01
+	idUnconnectedPong = 0x1c
+)
+
+// raknetMagic marks offline raknet messages
+var raknetMagic = []byte{0x00, 0xff, 0xff, 0x00, 0xfe, 0xfe, 0xfe, 0xfe, 0xfd, 0xfd, 0xfd, 0xfd, 0x12, 0x34, 0x56, 0x78}
+
+// BedrockResponse the normalized status of a bedrock edition server
+type BedrockResponse struct {
+	Host       string   `json:"host"`
+	Port       int      `json:"port"`
+	Edition    string   `json:"edition"`
+	MOTD       []string `json:"motd"`
+	RawMOTD    []string `json:"rawMotd"`
+	Protocol   int      `json:"protocol"`
+	Version    string   `json:"version"`
+	Online     int      `json:"online"`
+	Max        int      `json:"max"`
+	ServerId   string   `json:"serverId"`
+	GameMode   string   `json:"gameMode"`
+	GameModeId int      `json:"gameModeId"`
+	PortV4     int      `json:"portV4"`
+	PortV6     int      `json:"portV6"`
+	Latency    int64    `json:"latency"`
+}
+
+// PingBedrock queries the bedrock server at address, given as host or host:port, with a raknet unconnected ping.
+// Gives up once ctx is done or Timeout passed
+func (p *Pinger) PingBedrock(ctx context.Context, address string) (*BedrockResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout())
+	defer cancel()
+
+	t, err := p.resolve(ctx, address, "", "", DefaultBedrockPort)
+
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := p.dial(ctx, "udp", t)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer conn.Close()
+
+	start := time.Now()
+
+	ping := &bytes.Buffer{}
+	ping.WriteByte(idUnconnectedPing)
+	_ = binary.Write(ping, binary.BigEndian, start.UnixMilli())
+	ping.Write(raknetMagic)
+	_ = binary.Write(ping, binary.BigEndian, rand.Int63())
+
+	if _, err := conn.Write(ping.Bytes()); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 1500)
+	n, err := conn.Read(buf)
+
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := parsePong(buf[:n])
+
+	if err != nil {
+		return nil, err
+	}
+
+	response.Host = t.host
+	response.Port = t.port
+	response.Latency = time.Since(start).Milliseconds()
+
+	return response, nil
+}
+
+// parsePong parses an unconnected pong: id, time, server guid, magic and the length prefixed server id string
+func parsePong(packet []byte) (*BedrockResponse, error) {
+	const headerLength = 1 + 8 + 8 + 16 + 2
+
+	if len(packet) < headerLength || packet[0] != idUnconnectedPong {
+		return nil, errors.New("malformed unconnected pong")
+	}
+
+	if !bytes.Equal(packet[17:33], raknetMagic) {
+		return nil, errors.New("unconnected pong is missing the raknet magic")
+	}
+
+	length := int(binary.BigEndian.Uint16(packet[33:35]))
+
+	if len(packet) < headerLength+length {
+		return nil, errors.New("truncated unconnected pong")
+	}
+
+	//MCPE;motd;protocol;version;online;max;server id;sub motd;game mode;game mode id;port v4;port v6;
+	fields := strings.Split(string(packet[headerLength:headerLength+length]), ";")
+
+	if len(fields) < 6 {
+		return nil, fmt.Errorf("unconnected pong has %d fields, expected at least 6", len(fields))
+	}
+
+	field := func(i int) string {
+		if i < len(fields) {
+			return fields[i]
+		}
+
+		return ""
+	}
+
+	number := func(i int) int {
+		n, _ := strconv.Atoi(field(i))
+		return n
+	}
+
+	response := &BedrockResponse{
+		Edition:    field(0),
+		RawMOTD:    []string{field(1)},
+		Protocol:   number(2),
+		Version:    field(3),
+		Online:     number(4),
+		Max:        number(5),
+		ServerId:   field(6),
+		GameMode:   field(8),
+		GameModeId: number(9),
+		PortV4:     number(10),
+		PortV6:     number(11),
+	}
+
+	if field(7) != "" {
+		response.RawMOTD = append(response.RawMOTD, field(7))
+	}
+
+	//bedrock motds carry legacy § codes
+	for _, line := range response.RawMOTD {
+		response.MOTD = append(response.MOTD, motd.Plain(motd.ParseLegacy(line)))
+	}
+
+	return response, nil
+}
@@ -0,0 +1,118 @@
+package serverping
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// maxPacketLength upper bound for a status packet, generous enough for a response with a favicon
+const maxPacketLength = 1 << 21
+
+// writeVarInt appends a protocol VarInt, the two's complement value in groups of 7 bits, least significant first
+func writeVarInt(buf *bytes.Buffer, value int32) {
+	v := uint32(value)
+
+	for {
+		if v&^0x7f == 0 {
+			buf.WriteByte(byte(v))
+			return
+		}
+
+		buf.WriteByte(byte(v&0x7f | 0x80))
+		v >>= 7
+	}
+}
+
+// readVarInt reads a protocol VarInt of at most 5 bytes
+func readVarInt(r io.ByteReader) (int32, error) {
+	var value uint32
+
+	for i := 0; i < 5; i++ {
+		b, err := r.ReadByte()
+
+		if err != nil {
+			return 0, err
+		}
+
+		value |= uint32(b&0x7f) << (7 * i)
+
+		if b&0x80 == 0 {
+			return int32(value), nil
+		}
+	}
+
+	return 0, errors.New("varint is too big")
+}
+
+// writeString appends a VarInt length prefixed UTF-8 string
+func writeString(buf *bytes.Buffer, s string) {
+	writeVarInt(buf, int32(len(s)))
+	buf.WriteString(s)
+}
+
+// readString reads a VarInt length prefixed UTF-8 string
+func readString(r *bufio.Reader) (string, error) {
+	length, err := readVarInt(r)
+
+	if err != nil {
+		return "", err
+	}
+
+	if length < 0 || length > maxPacketLength {
+		return "", errors.New("string length out of bounds")
+	}
+
+	data := make([]byte, length)
+	_, err = io.ReadFull(r, data)
+
+	return string(data), err
+}
+
+// writePacket frames the packet id and payload with their VarInt length
+func writePacket(w io.Writer, id int32, payload []byte) error {
+	body := &bytes.Buffer{}
+	writeVarInt(body, id)
+	body.Write(payload)
+
+	frame := &bytes.Buffer{}
+	writeVarInt(frame, int32(body.Len()))
+	frame.Write(body.Bytes())
+
+	_, err := w.Write(frame.Bytes())
+	return err
+}
+
+// readPacket reads a length framed packet and returns its id and a reader over the payload
+func readPacket(r *bufio.Reader) (int32, *bufio.Reader, error) {
+	length, err := readVarInt(r)
+
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if length <= 0 || length > maxPacketLength {
+		return 0, nil, errors.New("packet length out of bounds")
+	}
+
+	data := make([]byte, length)
+	_, err = io.ReadFull(r, data)
+
+	if err != nil {
+		return 0, nil, err
+	}
+
+	payload := bufio.NewReader(bytes.NewReader(data))
+	id, err := readVarInt(payload)
+
+	return id, payload, err
+}
+
+// writeUTF16 appends a string as UTF-16BE code units, as used by the legacy protocol
+func writeUTF16(buf *bytes.Buffer, s string) {
+	for _, unit := range encodeUTF16(s) {
+		_ = binary.Write(buf, binary.BigEndian, unit)
+	}
+}
@@ -0,0 +1,430 @@
+package serverping
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"bed.gg/minecraft-api/v2/src/motd"
+)
+
+// DefaultPort default port of java edition servers
+const DefaultPort = 25565
+
+// handshakeProtocol protocol version sent in the handshake, servers answer status requests for any version
+const handshakeProtocol = 47
+
+type Player struct {
+	Name string `json:"name"`
+	Id   string `json:"id"`
+}
+
+// StatusResponse the status json sent by the server
+type StatusResponse struct {
+	Version struct {
+		Name     string `json:"name"`
+		Protocol int    `json:"protocol"`
+	} `json:"version"`
+	Players struct {
+		Max    int      `json:"max"`
+		Online int      `json:"online"`
+		Sample []Player `json:"sample"`
+	} `json:"players"`
+	Description json.RawMessage `json:"description"`
+	Favicon     string          `json:"favicon"`
+}
+
+// Response the normalized status of a java edition server
+type Response struct {
+	Host        string          `json:"host"`
+	Port        int             `json:"port"`
+	Version     string          `json:"version"`
+	Protocol    int             `json:"protocol"`
+	Online      int             `json:"online"`
+	Max         int             `json:"max"`
+	Players     []Player        `json:"players"`
+	MOTD        string          `json:"motd"`
+	Description json.RawMessage `json:"description,omitempty"`
+	Favicon     string          `json:"favicon,omitempty"`
+	Latency     int64           `json:"latency"`
+	Legacy      bool            `json:"legacy"`
+}
+
+// Pinger queries the status of java edition servers with the Server List Ping protocol
+type Pinger struct {
+	// Timeout bounds resolving, dialing and the whole exchange with the server
+	Timeout time.Duration
+	// LocalIP optional source address to dial from
+	LocalIP net.IP
+	// Resolver used for SRV and address lookups, net.DefaultResolver when nil
+	Resolver *net.Resolver
+	// AllowPrivate permits pinging loopback and private network addresses
+	AllowPrivate bool
+}
+
+// target a resolved server address
+type target struct {
+	host string
+	ip   net.IP
+	port int
+}
+
+// Ping queries the server at address, given as host or host:port, falling back to the legacy 1.6 ping.
+// Gives up once ctx is done or Timeout passed
+func (p *Pinger) Ping(ctx context.Context, address string) (*Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout())
+	defer cancel()
+
+	t, err := p.resolve(ctx, address, "minecraft", "tcp", DefaultPort)
+
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := p.pingModern(ctx, t)
+
+	if err == nil {
+		return response, nil
+	}
+
+	//servers older than 1.7 do not understand the handshake
+	legacyResponse, legacyErr := p.pingLegacy(ctx, t)
+
+	if legacyErr != nil {
+		return nil, err
+	}
+
+	return legacyResponse, nil
+}
+
+func (p *Pinger) timeout() time.Duration {
+	if p.Timeout <= 0 {
+		return 5 * time.Second
+	}
+
+	return p.Timeout
+}
+
+func (p *Pinger) resolver() *net.Resolver {
+	if p.Resolver == nil {
+		return net.DefaultResolver
+	}
+
+	return p.Resolver
+}
+
+// SplitAddress splits host:port, the port is 0 when address has none
+func SplitAddress(address string) (string, int, error) {
+	if !strings.Contains(address, ":") {
+		return address, 0, nil
+	}
+
+	host, portString, err := net.SplitHostPort(address)
+
+	if err != nil {
+		return "", 0, err
+	}
+
+	port, err := strconv.Atoi(portString)
+
+	if err != nil || port <= 0 || port > 65535 {
+		return "", 0, fmt.Errorf("bad port: %s", portString)
+	}
+
+	return host, port, nil
+}
+
+// resolve looks up the SRV record of the host when no port is given, and the ip address to dial
+func (p *Pinger) resolve(ctx context.Context, address string, service string, proto string, defaultPort int) (*target, error) {
+	host, port, err := SplitAddress(address)
+
+	if err != nil {
+		return nil, err
+	}
+
+	t := &target{
+		host: host,
+		port: port,
+	}
+
+	dialHost := host
+
+	if port == 0 {
+		t.port = defaultPort
+
+		if net.ParseIP(host) == nil && service != "" {
+			_, records, err := p.resolver().LookupSRV(ctx, service, proto, host)
+
+			if err == nil && len(records) > 0 {
+				dialHost = strings.TrimSuffix(records[0].Target, ".")
+				t.port = int(records[0].Port)
+			}
+		}
+	}
+
+	addrs, err := p.resolver().LookupIPAddr(ctx, dialHost)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no addresses found for %s", dialHost)
+	}
+
+	t.ip = addrs[0].IP
+
+	if !p.AllowPrivate && isPrivate(t.ip) {
+		return nil, fmt.Errorf("refusing to ping private address %s", t.ip)
+	}
+
+	return t, nil
+}
+
+// isPrivate reports if the ip points into our own network
+func isPrivate(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast()
+}
+
+// dial opens a connection to the target from the configured source address
+func (p *Pinger) dial(ctx context.Context, network string, t *target) (net.Conn, error) {
+	dialer := &net.Dialer{}
+
+	if p.LocalIP != nil {
+		switch network {
+		case "udp":
+			dialer.LocalAddr = &net.UDPAddr{IP: p.LocalIP}
+		default:
+			dialer.LocalAddr = &net.TCPAddr{IP: p.LocalIP}
+		}
+	}
+
+	conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(t.ip.String(), strconv.Itoa(t.port)))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	return conn, nil
+}
+
+// pingModern performs the 1.7+ handshake, status request and ping
+func (p *Pinger) pingModern(ctx context.Context, t *target) (*Response, error) {
+	conn, err := p.dial(ctx, "tcp", t)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer conn.Close()
+
+	//handshake with the next state set to status
+	handshake := &bytes.Buffer{}
+	writeVarInt(handshake, handshakeProtocol)
+	writeString(handshake, t.host)
+	_ = binary.Write(handshake, binary.BigEndian, uint16(t.port))
+	writeVarInt(handshake, 1)
+
+	if err := writePacket(conn, 0x00, handshake.Bytes()); err != nil {
+		return nil, err
+	}
+
+	//status request
+	if err := writePacket(conn, 0x00, nil); err != nil {
+		return nil, err
+	}
+
+	r := bufio.NewReader(conn)
+	id, payload, err := readPacket(r)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if id != 0x00 {
+		return nil, fmt.Errorf("unexpected status response packet id %d", id)
+	}
+
+	statusJson, err := readString(payload)
+
+	if err != nil {
+		return nil, err
+	}
+
+	status := &StatusResponse{}
+	err = json.Unmarshal([]byte(statusJson), status)
+
+	if err != nil {
+		return nil, err
+	}
+
+	response := &Response{
+		Host:        t.host,
+		Port:        t.port,
+		Version:     status.Version.Name,
+		Protocol:    status.Version.Protocol,
+		Online:      status.Players.Online,
+		Max:         status.Players.Max,
+		Players:     status.Players.Sample,
+		MOTD:        plainDescription(status.Description),
+		Description: status.Description,
+		Favicon:     status.Favicon,
+	}
+
+	if response.Players == nil {
+		response.Players = []Player{}
+	}
+
+	//measure the round trip, not every server answers the ping so failures are ignored
+	start := time.Now()
+	pingPayload := make([]byte, 8)
+	binary.BigEndian.PutUint64(pingPayload, uint64(start.UnixMilli()))
+
+	if err := writePacket(conn, 0x01, pingPayload); err == nil {
+		if id, _, err := readPacket(r); err == nil && id == 0x01 {
+			response.Latency = time.Since(start).Milliseconds()
+		}
+	}
+
+	return response, nil
+}
+
+// pingLegacy performs the 1.6 server list ping, which older servers answer with a kick packet
+func (p *Pinger) pingLegacy(ctx context.Context, t *target) (*Response, error) {
+	conn, err := p.dial(ctx, "tcp", t)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer conn.Close()
+
+	start := time.Now()
+
+	data := &bytes.Buffer{}
+	data.WriteByte(74)
+	_ = binary.Write(data, binary.BigEndian, uint16(len(encodeUTF16(t.host))))
+	writeUTF16(data, t.host)
+	_ = binary.Write(data, binary.BigEndian, int32(t.port))
+
+	request := &bytes.Buffer{}
+	request.Write([]byte{0xfe, 0x01, 0xfa})
+	_ = binary.Write(request, binary.BigEndian, uint16(len(encodeUTF16("MC|PingHost"))))
+	writeUTF16(request, "MC|PingHost")
+	_ = binary.Write(request, binary.BigEndian, uint16(data.Len()))
+	request.Write(data.Bytes())
+
+	if _, err := conn.Write(request.Bytes()); err != nil {
+		return nil, err
+	}
+
+	r := bufio.NewReader(conn)
+	id, err := r.ReadByte()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if id != 0xff {
+		return nil, fmt.Errorf("unexpected legacy response packet id %d", id)
+	}
+
+	var length uint16
+	if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+		return nil, err
+	}
+
+	units := make([]uint16, length)
+	if err := binary.Read(r, binary.BigEndian, units); err != nil {
+		return nil, err
+	}
+
+	response, err := parseLegacy(string(utf16.Decode(units)))
+
+	if err != nil {
+		return nil, err
+	}
+
+	response.Host = t.host
+	response.Port = t.port
+	response.Latency = time.Since(start).Milliseconds()
+
+	return response, nil
+}
+
+// parseLegacy parses the kick message of the legacy ping, in the 1.4+ or the beta 1.8 format
+func parseLegacy(message string) (*Response, error) {
+	response := &Response{
+		Players: []Player{},
+		Legacy:  true,
+	}
+
+	var fields []string
+
+	if strings.HasPrefix(message, "§1\x00") {
+		//§1 protocol version motd online max
+		fields = strings.Split(message, "\x00")
+
+		if len(fields) != 6 {
+			return nil, errors.New("malformed legacy ping response")
+		}
+
+		response.Protocol, _ = strconv.Atoi(fields[1])
+		response.Version = fields[2]
+		response.MOTD = fields[3]
+		fields = fields[4:]
+	} else {
+		//motd§online§max
+		fields = strings.Split(message, "§")
+
+		if len(fields) < 3 {
+			return nil, errors.New("malformed legacy ping response")
+		}
+
+		response.MOTD = strings.Join(fields[:len(fields)-2], "§")
+		fields = fields[len(fields)-2:]
+	}
+
+	//keep the formatted motd as a description so it can be rendered like a modern one
+	response.Description, _ = json.Marshal(response.MOTD)
+	response.MOTD = motd.Plain(motd.ParseLegacy(response.MOTD))
+
+	var err error
+	if response.Online, err = strconv.Atoi(fields[0]); err != nil {
+		return nil, err
+	}
+
+	if response.Max, err = strconv.Atoi(fields[1]); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// plainDescription flattens the description chat component to its text
+func plainDescription(raw json.RawMessage) string {
+	segments, err := motd.Parse(raw)
+
+	if err != nil {
+		return ""
+	}
+
+	return motd.Plain(segments)
+}
+
+func encodeUTF16(s string) []uint16 {
+	return utf16.Encode([]rune(s))
+}
@@ -0,0 +1,228 @@
+package serverping
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+	"unicode/utf16"
+)
+
+// fakeServer accepts connections on a local port and answers them with handle
+func fakeServer(t *testing.T, handle func(conn net.Conn)) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		_ = listener.Close()
+	})
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+				_ = conn.SetDeadline(time.Now().Add(2 * time.Second))
+				handle(conn)
+			}()
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+// modernHandler answers the handshake, status request and ping like a 1.7+ server
+func modernHandler(t *testing.T, status string) func(conn net.Conn) {
+	return func(conn net.Conn) {
+		r := bufio.NewReader(conn)
+
+		//handshake
+		id, payload, err := readPacket(r)
+		if err != nil || id != 0x00 {
+			return
+		}
+
+		if _, err := readVarInt(payload); err != nil {
+			t.Error(err)
+		}
+
+		if host, err := readString(payload); err != nil || host != "127.0.0.1" {
+			t.Errorf("unexpected handshake host %q: %v", host, err)
+		}
+
+		//status request
+		if id, _, err := readPacket(r); err != nil || id != 0x00 {
+			t.Errorf("unexpected status request %d: %v", id, err)
+			return
+		}
+
+		response := &bytes.Buffer{}
+		writeString(response, status)
+		_ = writePacket(conn, 0x00, response.Bytes())
+
+		//ping
+		id, payload, err = readPacket(r)
+		if err != nil || id != 0x01 {
+			return
+		}
+
+		echo, _ := io.ReadAll(payload)
+		_ = writePacket(conn, 0x01, echo)
+	}
+}
+
+func TestPingModern(t *testing.T) {
+	status := `{"version":{"name":"1.19.2","protocol":760},"players":{"max":100,"online":2,"sample":[{"name":"Notch","id":"069a79f4-44e9-4726-a5be-fca90e38aaf5"}]},"description":{"text":"A ","extra":[{"text":"§aMinecraft"}," Server"]},"favicon":"data:image/png;base64,AAAA"}`
+	address := fakeServer(t, modernHandler(t, status))
+
+	pinger := &Pinger{Timeout: 2 * time.Second, AllowPrivate: true}
+	response, err := pinger.Ping(context.Background(), address)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.Version != "1.19.2" || response.Protocol != 760 {
+		t.Errorf("unexpected version %s (%d)", response.Version, response.Protocol)
+	}
+
+	if response.Online != 2 || response.Max != 100 {
+		t.Errorf("unexpected players %d/%d", response.Online, response.Max)
+	}
+
+	if len(response.Players) != 1 || response.Players[0].Id != "069a79f4-44e9-4726-a5be-fca90e38aaf5" {
+		t.Errorf("unexpected player sample %v", response.Players)
+	}
+
+	if response.MOTD != "A Minecraft Server" {
+		t.Errorf("unexpected motd %q", response.MOTD)
+	}
+
+	if response.Legacy {
+		t.Error("modern server reported as legacy")
+	}
+}
+
+func TestPingLegacy(t *testing.T) {
+	address := fakeServer(t, func(conn net.Conn) {
+		r := bufio.NewReader(conn)
+
+		//legacy clients open with 0xfe, modern handshakes are dropped like an old server would
+		if b, err := r.ReadByte(); err != nil || b != 0xfe {
+			return
+		}
+
+		units := utf16.Encode([]rune("§1\x0074\x001.6.4\x00§eA Legacy Server\x005\x0020"))
+
+		kick := &bytes.Buffer{}
+		kick.WriteByte(0xff)
+		_ = binary.Write(kick, binary.BigEndian, uint16(len(units)))
+		_ = binary.Write(kick, binary.BigEndian, units)
+		_, _ = conn.Write(kick.Bytes())
+	})
+
+	pinger := &Pinger{Timeout: 2 * time.Second, AllowPrivate: true}
+	response, err := pinger.Ping(context.Background(), address)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !response.Legacy || response.Version != "1.6.4" || response.Protocol != 74 {
+		t.Errorf("unexpected legacy version %s (%d)", response.Version, response.Protocol)
+	}
+
+	if response.MOTD != "A Legacy Server" || response.Online != 5 || response.Max != 20 {
+		t.Errorf("unexpected legacy response %+v", response)
+	}
+}
+
+func TestPingPrivate(t *testing.T) {
+	pinger := &Pinger{Timeout: time.Second}
+
+	if _, err := pinger.Ping(context.Background(), "127.0.0.1:25565"); err == nil {
+		t.Error("private address was pinged")
+	}
+}
+
+func TestPingContext(t *testing.T) {
+	//a server that accepts the connection but never answers
+	address := fakeServer(t, func(conn net.Conn) {
+		_, _ = io.Copy(io.Discard, conn)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	//the deadline of the request cuts the ping short of the timeout of the pinger
+	pinger := &Pinger{Timeout: 5 * time.Second, AllowPrivate: true}
+	start := time.Now()
+
+	if _, err := pinger.Ping(ctx, address); err == nil {
+		t.Fatal("ping of a silent server did not fail")
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Ping took %v, want it to stop with its context", elapsed)
+	}
+}
+
+func TestPingBedrock(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	go func() {
+		buf := make([]byte, 1500)
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+
+		if n != 33 || buf[0] != idUnconnectedPing || !bytes.Equal(buf[9:25], raknetMagic) {
+			t.Errorf("malformed unconnected ping % x", buf[:n])
+			return
+		}
+
+		serverId := "MCPE;Dedicated Server;560;1.19.50;3;10;13253860892328930865;Bedrock level;Survival;1;19132;19133;"
+
+		pong := &bytes.Buffer{}
+		pong.WriteByte(idUnconnectedPong)
+		pong.Write(buf[1:9])
+		_ = binary.Write(pong, binary.BigEndian, int64(13253860892328930865&0x7fffffffffffffff))
+		pong.Write(raknetMagic)
+		_ = binary.Write(pong, binary.BigEndian, uint16(len(serverId)))
+		pong.WriteString(serverId)
+
+		_, _ = conn.WriteTo(pong.Bytes(), addr)
+	}()
+
+	pinger := &Pinger{Timeout: 2 * time.Second, AllowPrivate: true}
+	response, err := pinger.PingBedrock(context.Background(), conn.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.Edition != "MCPE" || response.Version != "1.19.50" || response.Protocol != 560 {
+		t.Errorf("unexpected version %s %s (%d)", response.Edition, response.Version, response.Protocol)
+	}
+
+	if len(response.MOTD) != 2 || response.MOTD[0] != "Dedicated Server" || response.MOTD[1] != "Bedrock level" {
+		t.Errorf("unexpected motd %v", response.MOTD)
+	}
+
+	if response.Online != 3 || response.Max != 10 || response.GameMode != "Survival" || response.PortV4 != 19132 {
+		t.Errorf("unexpected bedrock response %+v", response)
+	}
+}
@@ -0,0 +1,175 @@
+package yggdrasil
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// PublicKeysUrl where mojang publishes the public keys signing profile properties
+const PublicKeysUrl = "https://api.minecraftservices.com/publickeys"
+
+// ErrNoKey there is no public key to verify against, the profile may well be genuine
+var ErrNoKey = errors.New("not verified (no key)")
+
+// ErrInvalidSignature the signature does not match the property it is attached to
+var ErrInvalidSignature = errors.New("signature invalid")
+
+// Verifier checks the signatures mojang attaches to profile properties when requested with ?unsigned=false
+type Verifier struct {
+	Key *rsa.PublicKey
+}
+
+// NewVerifier creates a Verifier from the yggdrasil session public key stored as PEM at path
+func NewVerifier(path string) (*Verifier, error) {
+	key, err := LoadPublicKey(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Verifier{
+		Key: key,
+	}, nil
+}
+
+// LoadVerifier creates a Verifier from the PEM file at path, or when path is empty from the keys published at url
+func LoadVerifier(ctx context.Context, path string, url string) (*Verifier, error) {
+	if path != "" {
+		return NewVerifier(path)
+	}
+
+	return FetchVerifier(ctx, url)
+}
+
+// FetchVerifier creates a Verifier from the first profile property key mojang publishes at url, empty uses PublicKeysUrl
+func FetchVerifier(ctx context.Context, url string) (*Verifier, error) {
+	if url == "" {
+		url = PublicKeysUrl
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := http.DefaultClient.Do(req)
+
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching public keys from %s returned %d", url, res.StatusCode)
+	}
+
+	keys := &struct {
+		ProfilePropertyKeys []struct {
+			PublicKey string `json:"publicKey"`
+		} `json:"profilePropertyKeys"`
+	}{}
+
+	if err := json.NewDecoder(res.Body).Decode(keys); err != nil {
+		return nil, err
+	}
+
+	if len(keys.ProfilePropertyKeys) == 0 {
+		return nil, fmt.Errorf("no profile property keys published at %s", url)
+	}
+
+	//the keys are base64 encoded PKIX DER
+	der, err := base64.StdEncoding.DecodeString(keys.ProfilePropertyKeys[0].PublicKey)
+
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := x509.ParsePKIXPublicKey(der)
+
+	if err != nil {
+		return nil, err
+	}
+
+	rsaKey, ok := key.(*rsa.PublicKey)
+
+	if !ok {
+		return nil, fmt.Errorf("public key published at %s is not an RSA key", url)
+	}
+
+	return &Verifier{
+		Key: rsaKey,
+	}, nil
+}
+
+// LoadPublicKey reads an RSA public key from a PEM file, accepting both PKIX and PKCS#1 encodings
+func LoadPublicKey(path string) (*rsa.PublicKey, error) {
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found in %s", path)
+	}
+
+	switch block.Type {
+	case "PUBLIC KEY":
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+
+		if err != nil {
+			return nil, err
+		}
+
+		rsaKey, ok := key.(*rsa.PublicKey)
+
+		if !ok {
+			return nil, fmt.Errorf("public key in %s is not an RSA key", path)
+		}
+
+		return rsaKey, nil
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unexpected PEM block type %q in %s", block.Type, path)
+	}
+}
+
+// Verify checks the base64 signature of a property value, mojang signs with SHA1withRSA.
+// Returns ErrNoKey without a key and an error wrapping ErrInvalidSignature when the signature does not match
+func (v *Verifier) Verify(value string, signature string) error {
+	if v == nil || v.Key == nil {
+		return ErrNoKey
+	}
+
+	if signature == "" {
+		return fmt.Errorf("%w: property is not signed", ErrInvalidSignature)
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(signature)
+
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+
+	digest := sha1.Sum([]byte(value))
+
+	if err := rsa.VerifyPKCS1v15(v.Key, crypto.SHA1, digest[:], sig); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package yggdrasil
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "yggdrasil_session_pubkey.pem")
+	err = os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verifier, err := NewVerifier(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value := base64.StdEncoding.EncodeToString([]byte(`{"profileId":"9032ea59caa14489a167c19a32f9771d"}`))
+	digest := sha1.Sum([]byte(value))
+
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA1, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signature := base64.StdEncoding.EncodeToString(sig)
+
+	if err := verifier.Verify(value, signature); err != nil {
+		t.Errorf("valid signature rejected: %v", err)
+	}
+
+	if err := verifier.Verify(value+"=", signature); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("tampered value = %v, want ErrInvalidSignature", err)
+	}
+
+	if err := verifier.Verify(value, ""); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("unsigned value = %v, want ErrInvalidSignature", err)
+	}
+
+	//without a key nothing is verified, which is not the same as a bad signature
+	var none *Verifier
+	if err := none.Verify(value, signature); err != ErrNoKey {
+		t.Errorf("Verify without key = %v, want ErrNoKey", err)
+	}
+}
+
+func TestFetchVerifier(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string][]map[string]string{
+			"profilePropertyKeys": {{"publicKey": base64.StdEncoding.EncodeToString(der)}},
+		})
+	}))
+	defer server.Close()
+
+	verifier, err := LoadVerifier(context.Background(), "", server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !verifier.Key.Equal(&key.PublicKey) {
+		t.Error("FetchVerifier did not load the published key")
+	}
+
+	//a configured key file takes precedence over the published keys
+	if _, err := LoadVerifier(context.Background(), filepath.Join(t.TempDir(), "missing.pem"), server.URL); !os.IsNotExist(err) {
+		t.Errorf("LoadVerifier with a missing key file = %v, want not exist", err)
+	}
+
+	empty := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"profilePropertyKeys":[]}`))
+	}))
+	defer empty.Close()
+
+	if _, err := FetchVerifier(context.Background(), empty.URL); err == nil {
+		t.Error("FetchVerifier without published keys did not fail")
+	}
+}
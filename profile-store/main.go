@@ -26,14 +26,14 @@ func (h *StoreHandler) PostSignIn(c *fiber.Ctx) error {
 		return c.SendStatus(fiber.StatusUnauthorized)
 	}
 
-	playerUUID := c.Params("uuid")
+	rawUUID := c.Params("uuid")
 	remoteAddr := c.Context().Conn().RemoteAddr().String()
 
-	h.Logger.Info("%s POST /signIn/%s", remoteAddr, playerUUID)
+	h.Logger.Info("%s POST /signIn/%s", remoteAddr, rawUUID)
 
-	if api.IsValidUUID(playerUUID) {
-		//publish the new signup
-		err := h.Handler.Rdb.Publish(h.Handler.Ctx, "signIn", playerUUID).Err()
+	if playerUUID, err := api.ParseUUID(rawUUID); err == nil {
+		//publish the new signup in its canonical form
+		err := h.Handler.Rdb.Publish(h.Handler.Ctx, "signIn", playerUUID.String()).Err()
 
 		if err != nil {
 			h.Logger.Error("SignUp Error: %v", err)
@@ -43,7 +43,7 @@ func (h *StoreHandler) PostSignIn(c *fiber.Ctx) error {
 		return c.SendStatus(fiber.StatusOK)
 	} else {
 		c.Status(fiber.StatusBadRequest)
-		return c.SendString(fmt.Sprintf("bad uuid: %s", playerUUID))
+		return c.SendString(fmt.Sprintf("bad uuid: %s", rawUUID))
 	}
 }
 